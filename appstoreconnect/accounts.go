@@ -0,0 +1,183 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Several API keys and vendor numbers, such as separate teams or a subsidiary, managed together
+
+var (
+	// ErrNoSuchProfile the accounts file has no profile by that name
+	ErrNoSuchProfile = errors.New("accounts: no such profile")
+
+	// ErrNoVendors a profile has no vendor numbers to fetch reports for
+	ErrNoVendors = errors.New("accounts: profile has no vendor numbers")
+)
+
+// Accounts is a set of named credential profiles, each with one or more vendor numbers
+type Accounts struct {
+	profiles map[string]*Credentials
+	opts     []ClientOption
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+type accountsFile struct {
+	Profiles map[string]*Credentials `yaml:"profiles"`
+}
+
+// NewAccounts creates accounts from profiles, clients are created with opts when first used.
+// A nil profile, like one left empty in an accounts file, has no credentials or vendors.
+func NewAccounts(profiles map[string]*Credentials, opts ...ClientOption) *Accounts {
+	ps := make(map[string]*Credentials, len(profiles))
+	for name, creds := range profiles {
+		if creds == nil {
+			creds = &Credentials{}
+		}
+		ps[name] = creds
+	}
+	return &Accounts{
+		profiles: ps,
+		opts:     opts,
+		clients:  map[string]*Client{},
+	}
+}
+
+// LoadAccounts reads a yaml file of named profiles, each in the format of a credentials file
+//
+//	profiles:
+//	  apps:
+//	    key_id: ...
+//	    issuer_id: ...
+//	    private_key: ...
+//	    vendor_number: "91032757"
+//	    vendor_numbers: ["91032758"]
+func LoadAccounts(path string, opts ...ClientOption) (*Accounts, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := accountsFile{}
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, err
+	}
+	a := NewAccounts(f.Profiles, opts...)
+	for _, creds := range a.profiles {
+		creds.resolveKeyPath(filepath.Dir(path))
+	}
+	return a, nil
+}
+
+// Names lists the profiles in sorted order
+func (a *Accounts) Names() []string {
+	names := []string{}
+	for name := range a.profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Credentials returns the credentials of a profile
+func (a *Accounts) Credentials(name string) (*Credentials, error) {
+	creds, ok := a.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchProfile, name)
+	}
+	return creds, nil
+}
+
+// Client returns the client of a profile, for its first vendor number
+func (a *Accounts) Client(name string) (*Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if c, ok := a.clients[name]; ok {
+		return c, nil
+	}
+	creds, err := a.Credentials(name)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(creds, a.opts...)
+	if err != nil {
+		return nil, err
+	}
+	a.clients[name] = c
+	return c, nil
+}
+
+// SalesReports fetches the TimeRange for every vendor of every profile and merges the rows,
+// each tagged with its vendor. Profiles without vendor numbers fail with ErrNoVendors.
+func (a *Accounts) SalesReports(timeRange *TimeRange, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
+	responses, err := fanOut(a.Names(), func(name string) (*SalesReportResponse, error) {
+		vendors := a.profiles[name].Vendors()
+		if len(vendors) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoVendors, name)
+		}
+		c, err := a.Client(name)
+		if err != nil {
+			return nil, err
+		}
+		return c.SalesReport.GetVendors(vendors, timeRange, reportType, reportSubType)
+	})
+
+	sr := SalesReportResponse{vendors: true}
+	for _, r := range responses {
+		sr.Reports = append(sr.Reports, r.Reports...)
+	}
+	return &sr, err
+}
+
+// FinanceReports fetches the TimeRange for every vendor of every profile and merges the rows,
+// each tagged with its vendor. Profiles without vendor numbers fail with ErrNoVendors.
+func (a *Accounts) FinanceReports(tr *TimeRange, regionCode string) (*FinanceReportResponse, error) {
+	responses, err := fanOut(a.Names(), func(name string) (*FinanceReportResponse, error) {
+		vendors := a.profiles[name].Vendors()
+		if len(vendors) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoVendors, name)
+		}
+		c, err := a.Client(name)
+		if err != nil {
+			return nil, err
+		}
+		return c.FinanceReport.GetVendors(vendors, tr, regionCode)
+	})
+
+	ret := FinanceReportResponse{vendors: true}
+	for _, r := range responses {
+		ret.Reports = append(ret.Reports, r.Reports...)
+	}
+	return &ret, err
+}
+
+// fanOut calls fn for each key concurrently, returning the non nil results in the order of keys
+// and every error joined
+func fanOut[T any](keys []string, fn func(key string) (*T, error)) ([]*T, error) {
+	results := make([]*T, len(keys))
+	errs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Go(func() {
+			results[i], errs[i] = fn(key)
+		})
+	}
+	wg.Wait()
+
+	ret := []*T{}
+	for _, r := range results {
+		if r != nil {
+			ret = append(ret, r)
+		}
+	}
+	return ret, errors.Join(errs...)
+}
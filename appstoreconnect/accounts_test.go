@@ -0,0 +1,111 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const accountsYml = `profiles:
+  games:
+    key_id: KEY2
+    issuer_id: ISSUER
+    private_key: key
+    vendor_number: "200"
+  apps:
+    key_id: KEY1
+    issuer_id: ISSUER
+    private_key: key
+    vendor_number: "100"
+    vendor_numbers: ["101", "100"]
+`
+
+func TestLoadAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.yml")
+	if err := os.WriteFile(path, []byte(accountsYml), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := LoadAccounts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := a.Names(); !reflect.DeepEqual(names, []string{"apps", "games"}) {
+		t.Error("unexpected profiles", names)
+	}
+
+	creds, err := a.Credentials("apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vendors := creds.Vendors(); !reflect.DeepEqual(vendors, []string{"100", "101"}) {
+		t.Error("unexpected vendors", vendors)
+	}
+
+	if _, err := a.Credentials("music"); !errors.Is(err, ErrNoSuchProfile) {
+		t.Error("expected no such profile", err)
+	}
+}
+
+func TestWithVendor(t *testing.T) {
+	c := &Client{vendorNumber: "100"}
+	c.initServices()
+	other := c.WithVendor("101")
+	if c.VendorNumber() != "100" || other.VendorNumber() != "101" {
+		t.Error("unexpected vendor numbers", c.VendorNumber(), other.VendorNumber())
+	}
+	if other.SalesReport.client != other || other.FinanceReport.client != other {
+		t.Error("expected services bound to the new client")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	failed := errors.New("failed")
+	results, err := fanOut([]string{"a", "b", "c"}, func(key string) (*string, error) {
+		if key == "b" {
+			return nil, failed
+		}
+		s := strings.ToUpper(key)
+		return &s, nil
+	})
+	if !errors.Is(err, failed) {
+		t.Error("expected joined error", err)
+	}
+	if len(results) != 2 || *results[0] != "A" || *results[1] != "C" {
+		t.Error("unexpected results", results)
+	}
+}
+
+func TestAccountsWithoutVendors(t *testing.T) {
+	a := NewAccounts(map[string]*Credentials{"apps": {KeyID: "KEY1", IssuerID: "ISSUER", PrivKey: "key"}})
+	tr := NewTimeRange(parseTime("2024-05-01"), parseTime("2024-05-01"), Daily)
+
+	if _, err := a.SalesReports(tr, ReportSales, SubReportSummary); !errors.Is(err, ErrNoVendors) {
+		t.Error("expected a profile without vendors to fail", err)
+	}
+	if _, err := a.FinanceReports(tr, "US"); !errors.Is(err, ErrNoVendors) {
+		t.Error("expected a profile without vendors to fail", err)
+	}
+}
+
+func TestLoadAccountsEmptyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.yml")
+	if err := os.WriteFile(path, []byte("profiles:\n  apps:\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadAccounts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Client("apps"); err == nil {
+		t.Error("expected an empty profile to have no credentials")
+	}
+	tr := NewTimeRange(parseTime("2024-05-01"), parseTime("2024-05-01"), Daily)
+	if _, err := a.SalesReports(tr, ReportSales, SubReportSummary); !errors.Is(err, ErrNoVendors) {
+		t.Error("expected an empty profile to have no vendors", err)
+	}
+}
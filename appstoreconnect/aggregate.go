@@ -0,0 +1,210 @@
+package appstoreconnect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// Aggregation of sales report rows, the loops everyone writes after fetching a report
+
+var (
+	// ErrUnknownColumn the column name does not match any tsv tag or field of SalesReportItem
+	ErrUnknownColumn = errors.New("aggregate: unknown column")
+)
+
+// columnAliases are the short names accepted in addition to tsv tags and field names
+var columnAliases = map[string]string{
+	"country":  "Country Code",
+	"date":     "Begin Date",
+	"currency": "Currency of Proceeds",
+	"type":     "Product Type Identifier",
+	"apple_id": "Apple Identifier",
+}
+
+// SalesAggregate is a SalesReportResponse pivoted by a set of columns. Proceeds are only ever
+// summed within a single currency, so each group has one row per currency of proceeds.
+type SalesAggregate struct {
+	GroupBy []string
+	Rows    []*SalesAggregateRow
+}
+
+// SalesAggregateRow is the total of all the rows sharing a group and currency
+type SalesAggregateRow struct {
+	Group    []string
+	Currency string
+	Units    int
+	Proceeds float64
+}
+
+// Filter returns a response with only the rows keep returns true for
+func (s *SalesReportResponse) Filter(keep func(*SalesReportItem) bool) *SalesReportResponse {
+	ret := SalesReportResponse{vendors: s.vendors}
+	for _, r := range s.Reports {
+		if keep(r) {
+			ret.Reports = append(ret.Reports, r)
+		}
+	}
+	return &ret
+}
+
+// FilterProductTypes returns a response with only rows of the given product type identifiers
+// (e.g. "1F" for iPhone apps, "IA1" for in-app purchases)
+func (s *SalesReportResponse) FilterProductTypes(ids ...string) *SalesReportResponse {
+	return s.Filter(func(r *SalesReportItem) bool {
+		return slices.Contains(ids, r.ProductTypeIdentifier)
+	})
+}
+
+// Aggregate sums units and proceeds grouped by the given columns. Columns may be named by tsv
+// tag ("Country Code"), field name ("CountryCode") or alias ("country"), case insensitively.
+func (s *SalesReportResponse) Aggregate(groupBy ...string) (*SalesAggregate, error) {
+	indices := make([]int, len(groupBy))
+	headers := make([]string, len(groupBy))
+	for i, name := range groupBy {
+		idx, header, err := salesColumn(name)
+		if err != nil {
+			return nil, err
+		}
+		indices[i] = idx
+		headers[i] = header
+	}
+
+	ret := SalesAggregate{GroupBy: headers}
+	rows := map[string]*SalesAggregateRow{}
+	for _, r := range s.Reports {
+		v := reflect.ValueOf(r).Elem()
+		group := make([]string, len(indices))
+		for i, idx := range indices {
+			group[i] = v.Field(idx).String()
+		}
+
+		proceeds, err := r.Proceeds()
+		if err != nil {
+			return nil, err
+		}
+
+		key := strings.Join(append(slices.Clone(group), r.CurrencyOfProceeds), "\x00")
+		row, ok := rows[key]
+		if !ok {
+			row = &SalesAggregateRow{Group: group, Currency: r.CurrencyOfProceeds}
+			rows[key] = row
+			ret.Rows = append(ret.Rows, row)
+		}
+		row.Units += r.Units
+		row.Proceeds += proceeds
+	}
+
+	slices.SortFunc(ret.Rows, func(a, b *SalesAggregateRow) int {
+		if c := slices.Compare(a.Group, b.Group); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Currency, b.Currency)
+	})
+	return &ret, nil
+}
+
+// Proceeds is the total developer proceeds of the row, in CurrencyOfProceeds. Apple reports
+// proceeds per unit.
+func (s *SalesReportItem) Proceeds() (float64, error) {
+	if s.DeveloperProceeds == "" {
+		return 0, nil
+	}
+	p, err := strconv.ParseFloat(s.DeveloperProceeds, 64)
+	if err != nil {
+		return 0, err
+	}
+	return p * float64(s.Units), nil
+}
+
+// salesColumn resolves a column name to its SalesReportItem field index and tsv header
+func salesColumn(name string) (int, string, error) {
+	if alias, ok := columnAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	want := normalizeColumn(name)
+
+	t := reflect.TypeFor[SalesReportItem]()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("tsv")
+		if field.Type.Kind() != reflect.String {
+			continue
+		}
+		if tag == "-" {
+			tag = vendorNumberHeader
+		}
+		if normalizeColumn(tag) == want || normalizeColumn(field.Name) == want {
+			return i, tag, nil
+		}
+	}
+	return 0, "", fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+}
+
+func (a *SalesAggregate) GetHeader() []string {
+	return append(slices.Clone(a.GroupBy), "Currency of Proceeds", "Units", "Developer Proceeds")
+}
+
+func (r *SalesAggregateRow) Values() []string {
+	return append(slices.Clone(r.Group),
+		r.Currency,
+		strconv.Itoa(r.Units),
+		strconv.FormatFloat(r.Proceeds, 'f', 2, 64))
+}
+
+func (a *SalesAggregate) ToJson() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+func (a *SalesAggregate) ToCsv() ([]byte, error) {
+	return a.toDelimited(',')
+}
+
+func (a *SalesAggregate) ToTsv() ([]byte, error) {
+	return a.toDelimited('\t')
+}
+
+func (a *SalesAggregate) toDelimited(comma rune) ([]byte, error) {
+	b := bytes.Buffer{}
+	buf := bufio.NewWriter(&b)
+
+	w := csv.NewWriter(buf)
+	w.Comma = comma
+	if err := w.Write(a.GetHeader()); err != nil {
+		return nil, err
+	}
+
+	for _, r := range a.Rows {
+		if err := w.Write(r.Values()); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	buf.Flush()
+	return b.Bytes(), nil
+}
+
+func (a *SalesAggregate) ToEncoding(e encoding.Encoding) ([]byte, error) {
+	switch e {
+	case encoding.Json:
+		return a.ToJson()
+	case encoding.Csv:
+		return a.ToCsv()
+	case encoding.Tsv:
+		return a.ToTsv()
+	}
+	return nil, errors.New("I dont know how to encode that: " + e.String())
+}
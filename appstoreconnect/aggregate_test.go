@@ -0,0 +1,41 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	s := &SalesReportResponse{Reports: []*SalesReportItem{
+		{SKU: "a", CountryCode: "US", CurrencyOfProceeds: "USD", ProductTypeIdentifier: "1F", Units: 2, DeveloperProceeds: "0.70"},
+		{SKU: "a", CountryCode: "US", CurrencyOfProceeds: "USD", ProductTypeIdentifier: "1F", Units: -1, DeveloperProceeds: "0.70"},
+		{SKU: "a", CountryCode: "FR", CurrencyOfProceeds: "EUR", ProductTypeIdentifier: "1F", Units: 1, DeveloperProceeds: "0.60"},
+		{SKU: "b", CountryCode: "US", CurrencyOfProceeds: "USD", ProductTypeIdentifier: "IA1", Units: 4, DeveloperProceeds: "1.40"},
+	}}
+
+	a, err := s.Aggregate("sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*SalesAggregateRow{
+		{Group: []string{"a"}, Currency: "EUR", Units: 1, Proceeds: 0.6},
+		{Group: []string{"a"}, Currency: "USD", Units: 1, Proceeds: 0.7},
+		{Group: []string{"b"}, Currency: "USD", Units: 4, Proceeds: 5.6},
+	}
+	if !reflect.DeepEqual(a.GroupBy, []string{"SKU"}) || !reflect.DeepEqual(expected, a.Rows) {
+		t.Errorf("unexpected aggregate %+v", a.Rows)
+	}
+
+	a, err = s.FilterProductTypes("1F").Aggregate("Country Code", "ProductTypeIdentifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Rows) != 2 || a.Rows[0].Group[0] != "FR" || a.Rows[1].Units != 1 {
+		t.Errorf("unexpected filtered aggregate %+v", a.Rows)
+	}
+
+	if _, err := s.Aggregate("nope"); !errors.Is(err, ErrUnknownColumn) {
+		t.Error("expected unknown column error")
+	}
+}
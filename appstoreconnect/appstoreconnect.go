@@ -1,18 +1,20 @@
 package appstoreconnect
 
 import (
-	"compress/gzip"
+	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"gopkg.in/yaml.v2"
 )
 
@@ -29,6 +31,9 @@ var (
 
 	// ErrNoData the endpoint returned a 404, which means do data in that time range
 	ErrNoData = errors.New("no data for date range")
+
+	// ErrNotYetPublished the endpoint returned a 404 for a period Apple has not published yet
+	ErrNotYetPublished = errors.New("report not yet published for date range")
 )
 
 // Credentials holder for all the information needed to communicate with appstore connect api
@@ -37,19 +42,38 @@ type Credentials struct {
 	IssuerID     string `yaml:"issuer_id"`
 	PrivKey      string `yaml:"private_key"`
 	VendorNumber string `yaml:"vendor_number"`
+
+	// PrivKeyPath is a .p8 file used when PrivKey is empty, relative to the credentials file
+	PrivKeyPath string `yaml:"private_key_path"`
+
+	// KeyType is "team", the default, or "individual" for a user's key, which has no IssuerID
+	KeyType KeyType `yaml:"key_type"`
+
+	// Scope optionally restricts tokens to these requests, e.g. "GET /v1/salesReports"
+	Scope []string `yaml:"scope"`
+
+	// VendorNumbers are further vendors reachable with the same key
+	VendorNumbers []string `yaml:"vendor_numbers"`
 }
 
 // Client to use to communicate with the connect api
 type Client struct {
-	jwt           string
+	tokens        *tokenSource
+	baseURL       string
+	middleware    []Middleware
+	onRaw         func(*RawReport) error
 	vendorNumber  string
 	client        *http.Client
+	cache         Cache
+	cacheTTL      time.Duration
+	now           func() time.Time
 	SalesReport   *SalesReport
 	FinanceReport *FinanceReport
-
-	// TODO token expiry
 }
 
+// ClientOption configures optional behavior of a Client
+type ClientOption func(*Client)
+
 type service struct {
 	Path   string
 	Params map[string]string
@@ -57,36 +81,78 @@ type service struct {
 }
 
 // NewClient creates a new connect api client using the provided credential and config information
-func NewClient(creds *Credentials) (*Client, error) {
-	payload := jwt.RegisteredClaims{
-		Audience:  jwt.ClaimStrings{"appstoreconnect-v1"},
-		Issuer:    creds.IssuerID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, payload)
-	token.Header["kid"] = creds.KeyID
-
-	key, err := parseP8PrivKey([]byte(creds.PrivKey))
+func NewClient(creds *Credentials, opts ...ClientOption) (*Client, error) {
+	keyType, err := creds.keyType()
 	if err != nil {
-		log.Fatal("parse p8 priv key fail")
 		return nil, err
 	}
+
 	client := new(Client)
-	secretStr, err := token.SignedString(key)
-	client.jwt = secretStr
+	client.tokens = &tokenSource{
+		keyID:    creds.KeyID,
+		issuerID: creds.IssuerID,
+		keyType:  keyType,
+		scope:    creds.Scope,
+	}
 	client.vendorNumber = creds.VendorNumber
+	client.initServices()
+	client.initClient()
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if len(client.middleware) > 0 {
+		client.client.Transport = chainMiddleware(client.middleware, client.client.Transport)
+	}
 
+	if client.tokens.signer == nil {
+		key, err := creds.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		client.tokens.signer = key
+	}
+
+	// fail now rather than on the first request if the signer cannot sign
+	if _, err := client.tokens.Token(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// WithVendor returns a client for another vendor number reachable with the same key. It shares
+// the connection pool, cache and configuration of c.
+func (c *Client) WithVendor(vendorNumber string) *Client {
+	clone := *c
+	clone.vendorNumber = vendorNumber
+	clone.initServices()
+	return &clone
+}
+
+// VendorNumber is the vendor reports are requested for
+func (c *Client) VendorNumber() string {
+	return c.vendorNumber
+}
+
+func (c *Client) initServices() {
 	// Reuse a single struct instead of allocating one for each service on the heap
 	svc := &service{
-		client: client,
+		client: c,
 	}
 
-	client.SalesReport = (*SalesReport)(svc)
-	client.FinanceReport = (*FinanceReport)(svc)
+	c.SalesReport = (*SalesReport)(svc)
+	c.FinanceReport = (*FinanceReport)(svc)
+}
 
-	client.initClient()
-	return client, err
+// Vendors lists VendorNumber and VendorNumbers without duplicates
+func (c *Credentials) Vendors() []string {
+	vendors := []string{}
+	for _, v := range append([]string{c.VendorNumber}, c.VendorNumbers...) {
+		if v != "" && !slices.Contains(vendors, v) {
+			vendors = append(vendors, v)
+		}
+	}
+	return vendors
 }
 
 // NewCredentials creates a representation of the credentials needed to communicate with the connect api
@@ -109,17 +175,18 @@ func NewCredentialsFromFile(path string) (*Credentials, error) {
 		return nil, err
 	}
 	e := yaml.UnmarshalStrict(b, c)
+	c.resolveKeyPath(filepath.Dir(path))
 	return c, e
 }
 
 // NewClientFromCredentialsFile creates credentials and an app store connect client
 // given the location of a yaml file with credential information
-func NewClientFromCredentialsFile(path string) (*Client, error) {
+func NewClientFromCredentialsFile(path string, opts ...ClientOption) (*Client, error) {
 	creds, err := NewCredentialsFromFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(creds)
+	return NewClient(creds, opts...)
 }
 
 func (c *Client) initClient() {
@@ -133,51 +200,126 @@ func (c *Client) initClient() {
 	}
 }
 
-func makeURL(path string) string {
-	return baseURL + path
+// WithClock sets the clock used to decide whether reports should have been published yet
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+func (c *Client) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// WithBaseURL sends requests somewhere other than apple, such as an appstoreconnecttest.Server.
+// u is the prefix of every path, like "https://api.appstoreconnect.apple.com/v1/".
+func WithBaseURL(u string) ClientOption {
+	return func(c *Client) {
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		c.baseURL = u
+	}
 }
 
-// Get make a request to the Apple App Store Connect API
-func (c *Client) get(path string, params map[string]string) ([]byte, error) {
-	req, err := http.NewRequest("GET", makeURL(path), nil)
+func (c *Client) makeURL(path string) string {
+	if c.baseURL == "" {
+		return baseURL + path
+	}
+	return c.baseURL + path
+}
+
+// getRaw makes a request to the Apple App Store Connect API, answering from the cache when
+// possible, and returns the report file as apple served it after handing it to the raw handler
+func (c *Client) getRaw(op *Operation) (*RawReport, error) {
+	op.VendorNumber = c.vendorNumber
+	q := url.Values{}
+	for k, v := range op.Filters {
+		q.Add(k, v)
+	}
+	q.Add("filter[vendorNumber]", c.vendorNumber)
+
+	raw, err := c.cachedRaw(op, q)
 	if err != nil {
 		return nil, err
 	}
+	if c.onRaw != nil {
+		if err := c.onRaw(raw); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
 
-	req.Header.Add("Accept", "application/a-gzip")
-	req.Header.Add("Accept-Encoding", "gzip")
-	req.Header.Add("Authorization", "Bearer "+c.jwt)
+func (c *Client) cachedRaw(op *Operation, q url.Values) (*RawReport, error) {
+	var key string
+	if c.cache != nil {
+		key = cacheKey(op.Path, q)
+		b, ok, err := c.cache.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return newRawReport(op, c.makeURL(op.Path)+"?"+q.Encode(), nil, b, true), nil
+		}
+	}
 
-	q := req.URL.Query()
-	for k, v := range params {
-		q.Add(k, v)
+	raw, err := c.fetch(op, q)
+	if err != nil {
+		return nil, err
 	}
 
-	q.Add("filter[vendorNumber]", c.vendorNumber)
+	if c.cache != nil {
+		ttl, ok := c.cachePolicy(op.Filters)
+		if ok {
+			if err := c.cache.Put(key, raw.Body, ttl); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return raw, nil
+}
+
+// fetch returns the raw, still gzipped, report of a request
+func (c *Client) fetch(op *Operation, q url.Values) (*RawReport, error) {
+	ctx := withOperation(context.Background(), op)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.makeURL(op.Path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accept", "application/a-gzip")
+	req.Header.Add("Accept-Encoding", "gzip")
+	token, err := c.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
 
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, c.tokens.redactError(err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode > 299 && resp.StatusCode != 404 {
 		body, _ := io.ReadAll(resp.Body)
-		return body, errors.New(string(body))
+		return nil, c.tokens.redactError(errors.New(string(body)))
 	}
 
 	if resp.StatusCode == 404 {
 		return nil, ErrNoData
 	}
 
-	z, err := gzip.NewReader(resp.Body)
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	defer z.Close()
-
-	return io.ReadAll(z)
+	return newRawReport(op, req.URL.String(), resp.Header, b, false), nil
 }
 
 func parseP8PrivKey(bytes []byte) (*ecdsa.PrivateKey, error) {
@@ -0,0 +1,252 @@
+package appstoreconnect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Reports for periods Apple no longer revises never change, so the raw responses can be kept
+// and served again without asking Apple.
+
+// DefaultRecentCacheTTL is how long responses for periods Apple may still restate are cached
+const DefaultRecentCacheTTL = 6 * time.Hour
+
+// Cache stores the raw gzipped response bodies of report requests
+type Cache interface {
+	// Get returns the body stored under key, ok is false on a miss or when the entry expired
+	Get(key string) (body []byte, ok bool, err error)
+
+	// Put stores body under key. A ttl of zero keeps the entry forever.
+	Put(key string, body []byte, ttl time.Duration) error
+}
+
+// CacheStats counts the outcome of cache lookups
+type CacheStats struct {
+	Hits    int64
+	Misses  int64
+	Expired int64
+	Stores  int64
+}
+
+// CacheEntry describes a response stored in a DiskCache
+type CacheEntry struct {
+	Key       string            `json:"key"`
+	Path      string            `json:"path"`
+	Filters   map[string]string `json:"filters"`
+	StoredAt  time.Time         `json:"stored_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+	File      string            `json:"-"`
+}
+
+// DiskCache keeps each response as a .gz file with a .json metadata sidecar
+type DiskCache struct {
+	dir     string
+	hits    atomic.Int64
+	misses  atomic.Int64
+	expired atomic.Int64
+	stores  atomic.Int64
+}
+
+// WithCache serves repeated report requests from cache
+func WithCache(cache Cache) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithCacheTTL sets how long responses for recent, still revisable, periods are cached
+func WithCacheTTL(recent time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cacheTTL = recent
+	}
+}
+
+// NewDiskCache creates a cache in dir, creating the directory if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get returns the body stored under key
+func (d *DiskCache) Get(key string) ([]byte, bool, error) {
+	base := d.base(key)
+	entry, err := readCacheEntry(base + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		d.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		d.expired.Add(1)
+		d.misses.Add(1)
+		return nil, false, nil
+	}
+
+	b, err := os.ReadFile(base + ".gz")
+	if errors.Is(err, os.ErrNotExist) {
+		d.misses.Add(1)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	d.hits.Add(1)
+	return b, true, nil
+}
+
+// Put stores body under key
+func (d *DiskCache) Put(key string, body []byte, ttl time.Duration) error {
+	base := d.base(key)
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return err
+	}
+
+	path, filters := splitCacheKey(key)
+	entry := CacheEntry{
+		Key:      key,
+		Path:     path,
+		Filters:  filters,
+		StoredAt: time.Now().UTC(),
+	}
+	if ttl > 0 {
+		entry.ExpiresAt = entry.StoredAt.Add(ttl)
+	}
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// body first so a sidecar never points at a missing file
+	if err := writeFileAtomic(base+".gz", body); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(base+".json", meta); err != nil {
+		return err
+	}
+	d.stores.Add(1)
+	return nil
+}
+
+// Entries lists every response in the cache, including expired ones, so archived raw reports
+// can be parsed again
+func (d *DiskCache) Entries() ([]*CacheEntry, error) {
+	entries := []*CacheEntry{}
+	err := filepath.WalkDir(d.dir, func(path string, de os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		entry, err := readCacheEntry(path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Stats returns the lookup counters since the cache was created
+func (d *DiskCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:    d.hits.Load(),
+		Misses:  d.misses.Load(),
+		Expired: d.expired.Load(),
+		Stores:  d.stores.Load(),
+	}
+}
+
+func (d *DiskCache) base(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, h[:2], h)
+}
+
+func readCacheEntry(path string) (*CacheEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := CacheEntry{}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	entry.File = strings.TrimSuffix(path, ".json") + ".gz"
+	return &entry, nil
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cacheKey identifies a request by its path and all of its filters, in sorted order
+func cacheKey(path string, q url.Values) string {
+	return path + "?" + q.Encode()
+}
+
+func splitCacheKey(key string) (string, map[string]string) {
+	path, query, _ := strings.Cut(key, "?")
+	filters := map[string]string{}
+	q, _ := url.ParseQuery(query)
+	for k := range q {
+		filters[k] = q.Get(k)
+	}
+	return path, filters
+}
+
+// cachePolicy decides how long a response may be cached. Periods older than the restate window
+// are immutable and kept forever, recent ones expire after the recent TTL.
+func (c *Client) cachePolicy(params map[string]string) (time.Duration, bool) {
+	reportType := ReportType(params["filter[reportType]"])
+	f := Frequency(params["filter[frequency]"])
+	if reportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+
+	period, err := parseReportDate(params["filter[reportDate]"], f)
+	if err != nil {
+		return 0, false
+	}
+
+	recent := LatestAvailable(reportType, f, c.clock())
+	for i := 0; i < DefaultRestateWindow[f]; i++ {
+		recent = prevPeriod(recent, f)
+	}
+	if !period.After(recent) {
+		return 0, true
+	}
+
+	ttl := c.cacheTTL
+	if ttl == 0 {
+		ttl = DefaultRecentCacheTTL
+	}
+	return ttl, true
+}
@@ -0,0 +1,87 @@
+package appstoreconnect
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	d, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := cacheKey(pathSalesReports, url.Values{
+		"filter[reportDate]":   {"2019-09-08"},
+		"filter[vendorNumber]": {"123"},
+	})
+
+	if _, ok, _ := d.Get(key); ok {
+		t.Error("expected a miss on an empty cache")
+	}
+
+	if err := d.Put(key, []byte("body"), 0); err != nil {
+		t.Fatal(err)
+	}
+	b, ok, err := d.Get(key)
+	if err != nil || !ok || !bytes.Equal(b, []byte("body")) {
+		t.Error("expected a hit after put")
+	}
+
+	if err := d.Put(key, []byte("body"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := d.Get(key); ok {
+		t.Error("expected an expired entry to miss")
+	}
+
+	stats := d.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Expired != 1 || stats.Stores != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	entries, err := d.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Path != pathSalesReports || entries[0].Filters["filter[vendorNumber]"] != "123" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestCachePolicy(t *testing.T) {
+	c := &Client{}
+	ttl, ok := c.cachePolicy(map[string]string{
+		"filter[frequency]":  "DAILY",
+		"filter[reportDate]": "2019-09-08",
+		"filter[reportType]": "SALES",
+	})
+	if !ok || ttl != 0 {
+		t.Error("old periods should be cached forever")
+	}
+
+	yesterday := Today().AddDate(0, 0, -1)
+	ttl, ok = c.cachePolicy(map[string]string{
+		"filter[frequency]":  "DAILY",
+		"filter[reportDate]": yesterday.Format("2006-01-02"),
+		"filter[reportType]": "SALES",
+	})
+	if !ok || ttl != DefaultRecentCacheTTL {
+		t.Error("recent periods should expire")
+	}
+	// the restate window follows the client's clock
+	c.now = func() time.Time { return time.Date(2019, 9, 12, 12, 0, 0, 0, time.UTC) }
+	for day, expected := range map[string]time.Duration{"2019-09-10": DefaultRecentCacheTTL, "2019-09-01": 0} {
+		ttl, ok = c.cachePolicy(map[string]string{
+			"filter[frequency]":  "DAILY",
+			"filter[reportDate]": day,
+			"filter[reportType]": "SALES",
+		})
+		if !ok || ttl != expected {
+			t.Error("unexpected ttl", day, ttl)
+		}
+	}
+}
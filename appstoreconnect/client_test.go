@@ -0,0 +1,153 @@
+package appstoreconnect_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+// the fixtures of the internal tests
+const (
+	salesTsv   = appstoreconnect.SalesTsv
+	financeTsv = appstoreconnect.FinanceTsv
+)
+
+var may1 = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func newServerClient(t *testing.T) (*appstoreconnecttest.Server, *appstoreconnect.Client) {
+	t.Helper()
+	server := appstoreconnecttest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := server.Client(appstoreconnect.WithClock(func() time.Time {
+		return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func TestSalesReportGet(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+
+	r, err := client.SalesReport.Get(may1, appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Reports) != 1 || r.Reports[0].Units != 3 || r.Reports[0].VendorNumber != appstoreconnecttest.VendorNumber {
+		t.Errorf("unexpected report %+v", r.Reports)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatal("expected one request", requests)
+	}
+	req := requests[0]
+	if req.Path != "salesReports" || req.Filters["reportDate"] != "2024-05-01" || req.Filters["vendorNumber"] != appstoreconnecttest.VendorNumber {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.Header.Get("Accept") != "application/a-gzip" || !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+		t.Error("unexpected headers", req.Header)
+	}
+
+	// a published day without a report
+	_, err = client.SalesReport.Get(may1.AddDate(0, 0, 1), appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != appstoreconnect.ErrNoData {
+		t.Error("expected no data", err)
+	}
+
+	// a day apple has not published yet
+	_, err = client.SalesReport.Get(time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != appstoreconnect.ErrNotYetPublished {
+		t.Error("expected not yet published", err)
+	}
+}
+
+func TestFinanceReportGet(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddFinanceReport("2024-05", "US", financeTsv)
+
+	r, err := client.FinanceReport.Get(may1, "US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Reports) != 1 || r.Reports[0].ExtendedPartnerShare != "2.10" || r.Reports[0].VendorNumber != appstoreconnecttest.VendorNumber {
+		t.Errorf("unexpected report %+v", r.Reports)
+	}
+
+	if _, err := client.FinanceReport.Get(may1, "ZZ"); err != appstoreconnect.ErrNoData {
+		t.Error("expected no data", err)
+	}
+}
+
+func TestSalesReportGetVendors(t *testing.T) {
+	server, client := newServerClient(t)
+	for _, vendor := range []string{appstoreconnecttest.VendorNumber, "80000001"} {
+		server.AddReport("salesReports", appstoreconnecttest.Filters{
+			"vendorNumber":  vendor,
+			"frequency":     string(appstoreconnect.Daily),
+			"reportDate":    "2024-05-01",
+			"reportType":    string(appstoreconnect.ReportSales),
+			"reportSubType": string(appstoreconnect.SubReportSummary),
+		}, salesTsv)
+	}
+	tr := appstoreconnect.NewTimeRange(may1, may1, appstoreconnect.Daily)
+
+	// a single vendor's csv has apple's columns only
+	r, err := client.SalesReport.GetRange(tr, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := r.ToCsv()
+	if strings.Contains(string(b), "Vendor Number") {
+		t.Error("expected no vendor column", string(b))
+	}
+
+	r, err = client.SalesReport.GetVendors([]string{appstoreconnecttest.VendorNumber, "80000001"}, tr,
+		appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = r.ToTsv()
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "\tVendor Number") ||
+		!strings.HasSuffix(lines[1], "\t"+appstoreconnecttest.VendorNumber) || !strings.HasSuffix(lines[2], "\t80000001") {
+		t.Error("expected rows tagged with their vendor", lines)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		server.Fail(status, 1)
+		_, err := client.SalesReport.Get(may1, appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+		if err == nil || !strings.Contains(err.Error(), http.StatusText(status)) {
+			t.Error("expected an error for", status, err)
+		}
+	}
+
+	// a token signed by another key
+	creds := server.Credentials()
+	creds.PrivKey = string(appstoreconnecttest.NewSigner().P8())
+	other, err := appstoreconnect.NewClient(creds, appstoreconnect.WithBaseURL(server.URL+"/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = other.SalesReport.Get(may1, appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err == nil || errors.Is(err, appstoreconnect.ErrNoData) {
+		t.Error("expected unauthorized", err)
+	}
+
+	requests := server.Requests()
+	if last := requests[len(requests)-1]; last.Status != http.StatusUnauthorized {
+		t.Error("expected the forged token to be rejected", last.Status)
+	}
+}
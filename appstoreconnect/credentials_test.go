@@ -0,0 +1,96 @@
+package appstoreconnect
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeKey(t *testing.T) {
+	p8 := newP8(t)
+	block, _ := pem.Decode([]byte(p8))
+
+	forms := map[string]string{
+		"pem":     p8,
+		"escaped": strings.ReplaceAll(p8, "\n", `\n`),
+		"base64":  base64.StdEncoding.EncodeToString([]byte(p8)),
+		"der":     base64.StdEncoding.EncodeToString(block.Bytes),
+	}
+	for name, key := range forms {
+		c := Credentials{PrivKey: key}
+		if _, err := c.privateKey(); err != nil {
+			t.Error(name, err)
+		}
+	}
+
+	c := Credentials{PrivKey: "not a key"}
+	if _, err := c.privateKey(); err != ErrAuthKeyNotPem {
+		t.Error("expected not pem", err)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "AuthKey.p8"), []byte(newP8(t)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "credentials.yml")
+	yml := "key_id: FILEKEY\nissuer_id: FILEISSUER\nprivate_key_path: AuthKey.p8\nvendor_number: \"100\"\n"
+	if err := os.WriteFile(path, []byte(yml), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvKeyID, "")
+	t.Setenv(EnvIssuerID, "ENVISSUER")
+	t.Setenv(EnvVendorNumber, "200")
+	t.Setenv(EnvPrivateKey, "")
+	t.Setenv(EnvPrivateKeyPath, "")
+	t.Setenv(EnvKeyType, "")
+
+	creds, err := ResolveCredentials(&Credentials{VendorNumber: "300"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.KeyID != "FILEKEY" || creds.IssuerID != "ENVISSUER" || creds.VendorNumber != "300" {
+		t.Error("unexpected precedence", creds)
+	}
+	if creds.PrivKeyPath != filepath.Join(dir, "AuthKey.p8") {
+		t.Error("expected key path relative to the credentials file", creds.PrivKeyPath)
+	}
+	if _, err := creds.privateKey(); err != nil {
+		t.Error(err)
+	}
+
+	// the environment alone is not enough
+	_, err = ResolveCredentials(nil, filepath.Join(dir, "missing.yml"))
+	if !errors.Is(err, ErrIncompleteCredentials) {
+		t.Error("expected incomplete credentials", err)
+	}
+	if !strings.Contains(err.Error(), "key_id") || strings.Contains(err.Error(), "issuer_id") {
+		t.Error("unexpected missing fields", err)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		t.Error("a missing file should not be an error")
+	}
+}
+
+func newP8(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
@@ -0,0 +1,197 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedules for jobs which fetch reports: cron expressions, or following apple's publishing
+// schedule so a report is fetched as soon as it is out.
+
+var (
+	// ErrScheduleInvalid the cron expression could not be parsed
+	ErrScheduleInvalid = errors.New("schedule: invalid cron expression")
+)
+
+// Schedule decides when a job runs
+type Schedule interface {
+	// Next returns the first time after t the job should run, or the zero time if never
+	Next(t time.Time) time.Time
+}
+
+// cronSchedule matches minutes by a bit per value of each field
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	loc                           *time.Location
+	// anyDay is set when either day field is *, otherwise a day matching either field matches
+	anyDay bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+var cronShorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseCron parses a cron expression of minute, hour, day of month, month and day of week. Each
+// field is *, a value, a range like 1-5 or a list like 1,15, optionally with a step like */15.
+// Times are in ReportingLocation, like apple's publishing schedule, unless the expression starts
+// with CRON_TZ=<zone>. @hourly, @daily, @weekly, @monthly and @yearly are accepted too.
+func ParseCron(spec string) (Schedule, error) {
+	s := &cronSchedule{loc: ReportingLocation}
+
+	spec = strings.TrimSpace(spec)
+	if tz, rest, ok := strings.Cut(spec, " "); ok && strings.HasPrefix(tz, "CRON_TZ=") {
+		loc, err := time.LoadLocation(strings.TrimPrefix(tz, "CRON_TZ="))
+		if err != nil {
+			return nil, err
+		}
+		s.loc, spec = loc, strings.TrimSpace(rest)
+	}
+	if expanded, ok := cronShorthands[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleInvalid, spec)
+	}
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrScheduleInvalid, spec)
+		}
+		bits[i] = b
+	}
+	s.minute, s.hour, s.dom, s.month, s.dow = bits[0], bits[1], bits[2], bits[3], bits[4]
+	// sunday may be written 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.anyDay = fields[2] == "*" || fields[4] == "*"
+	return s, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		expr, step := part, 1
+		if e, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return 0, ErrScheduleInvalid
+			}
+			expr, step = e, n
+		}
+
+		top := f.max
+		if f.max == 6 {
+			// allow 7 for sunday
+			top = 7
+		}
+		lo, hi := f.min, top
+		if expr != "*" {
+			l, h, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = strconv.Atoi(l); err != nil {
+				return 0, ErrScheduleInvalid
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(h); err != nil {
+					return 0, ErrScheduleInvalid
+				}
+			} else if step > 1 {
+				// 5/15 runs from 5 to the end of the field
+				hi = top
+			}
+		}
+		if lo < f.min || hi > top || lo > hi {
+			return 0, ErrScheduleInvalid
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching minute after t, searching up to five years ahead
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if s.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+			continue
+		}
+		if s.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// publishedSchedule runs when the next report of any of its keys is published
+type publishedSchedule struct {
+	keys  []SyncKey
+	delay time.Duration
+}
+
+// PublishedSchedule runs delay after apple publishes the next report of any of keys, the delay
+// leaving apple some slack as reports are not always out exactly on time
+func PublishedSchedule(delay time.Duration, keys ...SyncKey) Schedule {
+	return &publishedSchedule{keys: keys, delay: delay}
+}
+
+// Next returns when the first report not yet published at t, plus the delay, is due
+func (s *publishedSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, key := range s.keys {
+		f := key.Frequency
+		if key.ReportType == ReportFinancial {
+			f = FiscalMonthly
+		}
+		latest := LatestAvailable(key.ReportType, f, t)
+		due := PublishedAt(key.ReportType, latest, f).Add(s.delay)
+		if !due.After(t) {
+			due = PublishedAt(key.ReportType, addFrequency(latest, f), f).Add(s.delay)
+		}
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
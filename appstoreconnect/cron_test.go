@@ -0,0 +1,79 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	pacific := func(s string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", s, ReportingLocation)
+		return tm
+	}
+	for _, c := range []struct {
+		spec, after, next string
+	}{
+		{"0 9 * * *", "2024-05-01 08:59", "2024-05-01 09:00"},
+		{"0 9 * * *", "2024-05-01 09:00", "2024-05-02 09:00"},
+		{"*/15 * * * *", "2024-05-01 09:01", "2024-05-01 09:15"},
+		{"30 8 * * 1-5", "2024-05-03 09:00", "2024-05-06 08:30"},
+		{"0 0 1,15 * *", "2024-05-02 00:00", "2024-05-15 00:00"},
+		{"0 12 * 2 7", "2024-05-02 00:00", "2025-02-02 12:00"},
+		{"@monthly", "2024-12-31 23:59", "2025-01-01 00:00"},
+		// either day field matches when both are restricted
+		{"0 0 13 * 5", "2024-05-01 00:00", "2024-05-03 00:00"},
+	} {
+		s, err := ParseCron(c.spec)
+		if err != nil {
+			t.Error(c.spec, err)
+			continue
+		}
+		if next := s.Next(pacific(c.after)); !next.Equal(pacific(c.next)) {
+			t.Error("unexpected next", c.spec, c.after, next)
+		}
+	}
+
+	s, err := ParseCron("CRON_TZ=UTC 0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)); !next.Equal(time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected next in utc", next)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(spec); !errors.Is(err, ErrScheduleInvalid) {
+			t.Error("expected an invalid schedule", spec)
+		}
+	}
+
+	if s, _ := ParseCron("0 0 30 2 *"); !s.Next(time.Now()).IsZero() {
+		t.Error("expected no next run on the 30th of february")
+	}
+}
+
+func TestPublishedSchedule(t *testing.T) {
+	s := PublishedSchedule(30*time.Minute,
+		SyncKey{ReportType: ReportSales, Frequency: Daily},
+		SyncKey{ReportType: ReportSales, Frequency: Weekly})
+
+	// the 9th is published at 08:00 PDT on the 10th
+	now := time.Date(2019, 9, 10, 14, 0, 0, 0, time.UTC)
+	if next := s.Next(now); !next.Equal(time.Date(2019, 9, 10, 15, 30, 0, 0, time.UTC)) {
+		t.Error("unexpected next run", next)
+	}
+	// within the delay of publishing
+	if next := s.Next(time.Date(2019, 9, 10, 15, 10, 0, 0, time.UTC)); !next.Equal(time.Date(2019, 9, 10, 15, 30, 0, 0, time.UTC)) {
+		t.Error("unexpected next run", next)
+	}
+	if next := s.Next(time.Date(2019, 9, 10, 15, 30, 0, 0, time.UTC)); !next.Equal(time.Date(2019, 9, 11, 15, 30, 0, 0, time.UTC)) {
+		t.Error("unexpected next run", next)
+	}
+
+	finance := PublishedSchedule(0, SyncKey{ReportType: ReportFinancial, RegionCode: "US"})
+	next := finance.Next(now)
+	if !next.After(now) || !next.Equal(PublishedAt(ReportFinancial, LatestAvailable(ReportFinancial, FiscalMonthly, now).AddDate(0, 1, 0), FiscalMonthly)) {
+		t.Error("unexpected next finance run", next)
+	}
+}
@@ -0,0 +1,202 @@
+package appstoreconnect
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sales rows are reported in the currency of each storefront. Converting proceeds with the rates
+// Apple used for each fiscal period's payout makes totals across countries meaningful. Rates are
+// kept by fiscal period, named by month like the payments, so sales in the last days of a calendar
+// month which fall in the next fiscal period use that period's rate.
+
+var (
+	// ErrNoExchangeRate there is no rate for a currency in a given fiscal period
+	ErrNoExchangeRate = errors.New("currency: no exchange rate")
+
+	// ErrNoPaymentSummary the input has no "Exchange Rate" table
+	ErrNoPaymentSummary = errors.New("currency: not a payment summary")
+)
+
+// anyMonth is the key of rates which apply to every period without a rate of its own
+const anyMonth = "*"
+
+var regionCurrency = regexp.MustCompile(`\(([A-Z]{3})\)\s*$`)
+
+// ExchangeRates converts amounts to Target using a rate per fiscal period and currency
+type ExchangeRates struct {
+	Target string
+	rates  map[string]map[string]float64
+}
+
+// NewExchangeRates creates an empty rate table converting to target, e.g. "USD"
+func NewExchangeRates(target string) *ExchangeRates {
+	return &ExchangeRates{
+		Target: target,
+		rates:  map[string]map[string]float64{},
+	}
+}
+
+// LoadExchangeRates reads a user supplied csv of month,currency,rate rows where rate is the
+// amount of target currency one unit of currency is worth and month names the fiscal period.
+// A month of "*" applies to any period without its own rate.
+func LoadExchangeRates(target string, r io.Reader) (*ExchangeRates, error) {
+	x := NewExchangeRates(target)
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+	cr.Comment = '#'
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return x, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		if err != nil && first {
+			// header row
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		month := strings.TrimSpace(rec[0])
+		if month != anyMonth {
+			t, err := time.Parse("2006-01", month)
+			if err != nil {
+				return nil, err
+			}
+			month = monthKey(t)
+		}
+		x.set(month, strings.TrimSpace(rec[1]), rate)
+	}
+}
+
+// Set records the rate of currency to Target for the fiscal period named after the month
+// containing month
+func (x *ExchangeRates) Set(month time.Time, currency string, rate float64) {
+	x.set(monthKey(month), currency, rate)
+}
+
+// AddPaymentSummary reads the rates from the "Payments and Financial Reports" csv Apple provides
+// for the fiscal period named after month, which lists each region's currency with the exchange
+// rate used for the payout. Finance reports only carry amounts in each region's own currency,
+// so the payment summary is the only source of these rates.
+func (x *ExchangeRates) AddPaymentSummary(month time.Time, r io.Reader) error {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	rateCol := -1
+	found := false
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if rateCol < 0 {
+			for i, col := range rec {
+				if strings.TrimSpace(col) == "Exchange Rate" {
+					rateCol = i
+				}
+			}
+			continue
+		}
+		if len(rec) <= rateCol {
+			continue
+		}
+
+		m := regionCurrency.FindStringSubmatch(rec[0])
+		if m == nil {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(rec[rateCol]), ",", ""), 64)
+		if err != nil || rate == 0 {
+			continue
+		}
+		x.Set(month, m[1], rate)
+		found = true
+	}
+
+	if !found {
+		return ErrNoPaymentSummary
+	}
+	return nil
+}
+
+// Rate returns what one unit of currency was worth in Target during the fiscal period
+// containing date
+func (x *ExchangeRates) Rate(date time.Time, currency string) (float64, error) {
+	if currency == x.Target {
+		return 1, nil
+	}
+	period := monthKey(AppleFiscalCalendar.PeriodOf(date).Month())
+	for _, month := range []string{period, anyMonth} {
+		if rate, ok := x.rates[month][currency]; ok {
+			return rate, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s %s", ErrNoExchangeRate, currency, period)
+}
+
+// Convert returns amount of currency in Target
+func (x *ExchangeRates) Convert(amount float64, currency string, date time.Time) (float64, error) {
+	rate, err := x.Rate(date, currency)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Normalize returns a copy of the response with developer proceeds converted to the rates'
+// target currency, using the fiscal period each row begins in
+func (s *SalesReportResponse) Normalize(rates *ExchangeRates) (*SalesReportResponse, error) {
+	ret := SalesReportResponse{vendors: s.vendors}
+	for _, r := range s.Reports {
+		c := r.Clone()
+		if c.DeveloperProceeds != "" && c.CurrencyOfProceeds != rates.Target {
+			date, err := time.Parse("01/02/2006", c.BeginDate)
+			if err != nil {
+				return nil, err
+			}
+			p, err := strconv.ParseFloat(c.DeveloperProceeds, 64)
+			if err != nil {
+				return nil, err
+			}
+			p, err = rates.Convert(p, c.CurrencyOfProceeds, date)
+			if err != nil {
+				return nil, err
+			}
+			c.DeveloperProceeds = strconv.FormatFloat(p, 'f', -1, 64)
+		}
+		c.CurrencyOfProceeds = rates.Target
+		ret.Reports = append(ret.Reports, c)
+	}
+	return &ret, nil
+}
+
+func (x *ExchangeRates) set(month string, currency string, rate float64) {
+	if x.rates[month] == nil {
+		x.rates[month] = map[string]float64{}
+	}
+	x.rates[month][currency] = rate
+}
+
+// monthKey names the fiscal period named after the month of t
+func monthKey(t time.Time) string {
+	return t.Format("2006-01")
+}
@@ -0,0 +1,58 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const paymentSummary = `"iTunes Connect - Payments and Financial Reports	(September, 2019)"
+Region (Currency),Units Sold,Earned,Pre-Tax Subtotal,Input Tax,Adjustments,Withholding Tax,Total Owed,Exchange Rate,Proceeds,Bank Account Currency
+Americas (USD),10,7.00,7.00,0.00,0.00,0.00,7.00,1.00000,7.00,USD
+Euro-Zone (EUR),5,3.00,3.00,0.00,0.00,0.00,3.00,1.10000,3.30,USD
+,,,,,,,,,10.30,USD
+`
+
+func TestNormalize(t *testing.T) {
+	rates := NewExchangeRates("USD")
+	if err := rates.AddPaymentSummary(parseTime("2019-09"), strings.NewReader(paymentSummary)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SalesReportResponse{Reports: []*SalesReportItem{
+		{SKU: "a", BeginDate: "09/08/2019", CurrencyOfProceeds: "USD", Units: 1, DeveloperProceeds: "0.70"},
+		{SKU: "a", BeginDate: "09/08/2019", CurrencyOfProceeds: "EUR", Units: 1, DeveloperProceeds: "1.00"},
+	}}
+	n, err := s.Normalize(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := n.Aggregate("sku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Rows) != 1 || a.Rows[0].Currency != "USD" || a.Rows[0].Units != 2 || a.Rows[0].Values()[3] != "1.80" {
+		t.Errorf("unexpected normalized aggregate %+v", a.Rows[0])
+	}
+
+	s.Reports[0].BeginDate = "10/01/2019"
+	s.Reports[0].CurrencyOfProceeds = "EUR"
+	if _, err := s.Normalize(rates); !errors.Is(err, ErrNoExchangeRate) {
+		t.Error("expected missing rate for october")
+	}
+
+	rates, err = LoadExchangeRates("USD", strings.NewReader("month,currency,rate\n*,EUR,1.2\n2019-09,EUR,1.1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := rates.Rate(parseTime("2019-10-01"), "EUR"); r != 1.2 {
+		t.Error("expected fallback rate", r)
+	}
+	if r, _ := rates.Rate(parseTime("2019-09-28"), "EUR"); r != 1.1 {
+		t.Error("expected the september period's rate", r)
+	}
+	// fiscal 2020 begins on sunday september 29th, in the period named october
+	if r, _ := rates.Rate(parseTime("2019-09-30"), "EUR"); r != 1.2 {
+		t.Error("expected the october period's rate", r)
+	}
+}
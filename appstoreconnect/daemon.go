@@ -0,0 +1,504 @@
+package appstoreconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Running jobs continuously. A Daemon syncs report streams into sinks on a schedule, by default
+// as apple publishes each report, and reports its progress on /healthz and /metrics. Progress is
+// checkpointed, so periods missed while it was stopped or failing are fetched on the next run.
+
+var (
+	// ErrDaemonConfig the daemon configuration is not valid
+	ErrDaemonConfig = errors.New("daemon: invalid config")
+
+	// ErrNoSuchJob the daemon has no job by that name
+	ErrNoSuchJob = errors.New("daemon: no such job")
+)
+
+// DefaultPublishDelay is how long after apple's publishing time jobs without a schedule run
+const DefaultPublishDelay = 30 * time.Minute
+
+// DaemonConfig is the configuration of a daemon, usually read with LoadDaemonConfig
+type DaemonConfig struct {
+	// Listen is the address serving /healthz and /metrics
+	Listen string `yaml:"listen"`
+	// Credentials is the credentials file, or an accounts file with Profile
+	Credentials string `yaml:"credentials"`
+	Profile     string `yaml:"profile"`
+	// Cache is a directory to cache reports in
+	Cache string `yaml:"cache"`
+	// Checkpoints is the file recording the progress of each job
+	Checkpoints string       `yaml:"checkpoints"`
+	Jobs        []*JobConfig `yaml:"jobs"`
+}
+
+// JobConfig describes the report streams a job syncs and where to
+type JobConfig struct {
+	Name string `yaml:"name"`
+	// ReportType is SALES if empty, or FINANCIAL for finance reports
+	ReportType    ReportType    `yaml:"report_type"`
+	ReportSubType ReportSubType `yaml:"report_sub_type"`
+	// Frequencies of sales reports, DAILY if empty
+	Frequencies []Frequency `yaml:"frequencies"`
+	// Vendors are the vendor numbers to sync, the client's if empty
+	Vendors []string `yaml:"vendors"`
+	// Regions of finance reports, US if empty
+	Regions []string `yaml:"regions"`
+	// Start is the first period synced, a date or expression like 2024-01 or last-3m. Without
+	// it streams start at the latest published period, which is logged and listed in the job's
+	// WithoutHistory.
+	Start string `yaml:"start"`
+	// Schedule is a cron expression, see ParseCron, or @published, the default, to run as
+	// apple publishes each report
+	Schedule string `yaml:"schedule"`
+	// Sinks are the directories or s3://bucket/prefix urls reports are exported to
+	Sinks []string `yaml:"sinks"`
+}
+
+// LoadDaemonConfig reads a yaml daemon configuration. Relative paths are relative to the file.
+//
+//	listen: ":9090"
+//	credentials: credentials.yml
+//	checkpoints: checkpoints.json
+//	jobs:
+//	  - name: sales
+//	    frequencies: [DAILY, WEEKLY]
+//	    start: 2024-01-01
+//	    sinks: [s3://bucket/apple]
+//	  - name: finance
+//	    report_type: FINANCIAL
+//	    regions: [US, EU]
+//	    schedule: "0 9 * * *"
+//	    sinks: [archive/]
+func LoadDaemonConfig(path string) (*DaemonConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &DaemonConfig{}
+	if err := yaml.UnmarshalStrict(b, config); err != nil {
+		return nil, err
+	}
+
+	if config.Listen == "" {
+		config.Listen = ":9090"
+	}
+	if config.Credentials == "" {
+		config.Credentials = "credentials.yml"
+	}
+	if config.Checkpoints == "" {
+		config.Checkpoints = "checkpoints.json"
+	}
+
+	dir := filepath.Dir(path)
+	config.Credentials = resolvePath(dir, config.Credentials)
+	config.Checkpoints = resolvePath(dir, config.Checkpoints)
+	if config.Cache != "" {
+		config.Cache = resolvePath(dir, config.Cache)
+	}
+	for _, j := range config.Jobs {
+		for i, sink := range j.Sinks {
+			if !strings.HasPrefix(sink, "s3://") {
+				j.Sinks[i] = resolvePath(dir, strings.TrimPrefix(sink, "file://"))
+			}
+		}
+	}
+	return config, nil
+}
+
+func resolvePath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// JobStatus is the state of a job, as reported by /healthz
+type JobStatus struct {
+	Name        string    `json:"name"`
+	Running     bool      `json:"running"`
+	LastRun     time.Time `json:"last_run"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Failures is the number of runs which failed since the last success
+	Failures int       `json:"consecutive_failures"`
+	NextRun  time.Time `json:"next_run"`
+	// Periods and Bytes count the reports exported since the daemon started
+	Periods int `json:"periods"`
+	Bytes   int `json:"bytes"`
+	// WithoutHistory lists the streams which had no start date or checkpoint, so were started at
+	// the latest published period without any earlier ones
+	WithoutHistory []string `json:"without_history,omitempty"`
+
+	runs     int
+	errors   int
+	duration time.Duration
+}
+
+// Daemon runs jobs on their schedules
+type Daemon struct {
+	client *Client
+	store  CheckpointStore
+	jobs   []*job
+
+	// RetryDelay is how long after a failure a job runs again, doubling with each further failure
+	// but never later than its next scheduled run
+	RetryDelay time.Duration
+
+	// Unhealthy is the number of consecutive failures of a job after which /healthz fails
+	Unhealthy int
+
+	// Logger logs each run, slog.Default() if nil
+	Logger *slog.Logger
+
+	// Now is the clock deciding which periods are published and when jobs run. Jobs wait for
+	// their next run with timers, which count real time, so it must follow the wall clock
+	// (an offset or a different location are fine).
+	Now func() time.Time
+}
+
+type job struct {
+	config   *JobConfig
+	keys     []SyncKey
+	schedule Schedule
+	sinks    []Sink
+
+	// run is held while the job runs
+	run sync.Mutex
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// NewDaemon creates a daemon fetching with client and checkpointing in store
+func NewDaemon(client *Client, store CheckpointStore, config *DaemonConfig) (*Daemon, error) {
+	d := &Daemon{
+		client:     client,
+		store:      store,
+		RetryDelay: 5 * time.Minute,
+		Unhealthy:  3,
+		Now:        time.Now,
+	}
+	names := map[string]bool{}
+	// checkpoints are kept per stream, so a stream synced by two jobs would only reach the sinks
+	// of whichever ran first
+	streams := map[SyncKey]string{}
+	for _, c := range config.Jobs {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("%w: jobs need unique names", ErrDaemonConfig)
+		}
+		names[c.Name] = true
+
+		j, err := d.newJob(c)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrDaemonConfig, c.Name, err)
+		}
+		for _, key := range j.keys {
+			if other, ok := streams[key]; ok {
+				return nil, fmt.Errorf("%w: jobs %s and %s both sync %s, list the sinks of one job instead", ErrDaemonConfig, other, c.Name, key)
+			}
+			streams[key] = c.Name
+		}
+		d.jobs = append(d.jobs, j)
+	}
+	return d, nil
+}
+
+func (d *Daemon) newJob(c *JobConfig) (*job, error) {
+	if c.ReportType == "" {
+		c.ReportType = ReportSales
+	}
+	if c.ReportSubType == "" && c.ReportType != ReportFinancial {
+		c.ReportSubType = SubReportSummary
+	}
+	if len(c.Frequencies) == 0 {
+		c.Frequencies = []Frequency{Daily}
+	}
+	if len(c.Regions) == 0 {
+		c.Regions = []string{"US"}
+	}
+	if len(c.Sinks) == 0 {
+		return nil, errors.New("no sinks")
+	}
+	if c.Start != "" {
+		if _, err := (&RangeParser{ReportType: c.ReportType}).Parse(c.Start); err != nil {
+			return nil, err
+		}
+	}
+
+	j := &job{config: c, status: JobStatus{Name: c.Name}}
+	vendors := c.Vendors
+	if len(vendors) == 0 {
+		vendors = []string{d.client.vendorNumber}
+	}
+	for _, vendor := range vendors {
+		if c.ReportType == ReportFinancial {
+			for _, region := range c.Regions {
+				j.keys = append(j.keys, SyncKey{VendorNumber: vendor, ReportType: ReportFinancial, Frequency: FiscalMonthly, RegionCode: region})
+			}
+			continue
+		}
+		for _, f := range c.Frequencies {
+			j.keys = append(j.keys, SyncKey{VendorNumber: vendor, ReportType: c.ReportType, ReportSubType: c.ReportSubType, Frequency: f})
+		}
+	}
+
+	for _, u := range c.Sinks {
+		sink, err := NewSink(u)
+		if err != nil {
+			return nil, err
+		}
+		j.sinks = append(j.sinks, sink)
+	}
+
+	if c.Schedule == "" || c.Schedule == "@published" {
+		j.schedule = PublishedSchedule(DefaultPublishDelay, j.keys...)
+		return j, nil
+	}
+	schedule, err := ParseCron(c.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	j.schedule = schedule
+	return j, nil
+}
+
+// Jobs lists the status of each job
+func (d *Daemon) Jobs() []JobStatus {
+	statuses := []JobStatus{}
+	for _, j := range d.jobs {
+		j.mu.Lock()
+		status := j.status
+		status.WithoutHistory = slices.Clone(status.WithoutHistory)
+		statuses = append(statuses, status)
+		j.mu.Unlock()
+	}
+	return statuses
+}
+
+// Run runs every job straight away, catching up on anything missed while stopped, and then on
+// its schedule until ctx is done. A job being run when ctx is done stops after the period it
+// is exporting.
+func (d *Daemon) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range d.jobs {
+		wg.Go(func() { d.loop(ctx, j) })
+	}
+	wg.Wait()
+}
+
+func (d *Daemon) loop(ctx context.Context, j *job) {
+	for {
+		err := d.runJob(ctx, j)
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := d.Now()
+		next := j.schedule.Next(now)
+		if err != nil {
+			j.mu.Lock()
+			retry := now.Add(d.RetryDelay << min(j.status.Failures-1, 10))
+			j.mu.Unlock()
+			if next.IsZero() || retry.Before(next) {
+				next = retry
+			}
+		}
+		j.mu.Lock()
+		j.status.NextRun = next
+		j.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunJob runs a job now, waiting for a run already in progress to finish first
+func (d *Daemon) RunJob(ctx context.Context, name string) error {
+	for _, j := range d.jobs {
+		if j.config.Name == name {
+			return d.runJob(ctx, j)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNoSuchJob, name)
+}
+
+// runJob syncs every stream of j, carrying on with the others when one fails
+func (d *Daemon) runJob(ctx context.Context, j *job) error {
+	j.run.Lock()
+	defer j.run.Unlock()
+
+	start := d.Now()
+	j.mu.Lock()
+	j.status.Running = true
+	j.status.LastRun = start
+	periods := j.status.Periods
+	j.mu.Unlock()
+
+	var errs []error
+	for _, key := range j.keys {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if err := d.syncKey(ctx, j, key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	err := errors.Join(errs...)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	j.status.runs++
+	j.status.duration = d.Now().Sub(start)
+	if err != nil {
+		j.status.errors++
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	} else {
+		j.status.Failures = 0
+		j.status.LastError = ""
+		j.status.LastSuccess = d.Now()
+	}
+	if err != nil {
+		d.logger().Warn("job failed", "job", j.config.Name, "periods", j.status.Periods-periods,
+			"duration", j.status.duration, "failures", j.status.Failures, "error", err)
+	} else {
+		d.logger().Info("job done", "job", j.config.Name, "periods", j.status.Periods-periods,
+			"duration", j.status.duration)
+	}
+	return err
+}
+
+func (d *Daemon) syncKey(ctx context.Context, j *job, key SyncKey) error {
+	syncer := NewSyncer(d.client, d.store)
+	syncer.Now = d.Now
+	if j.config.Start != "" {
+		tr, err := (&RangeParser{Now: d.Now, ReportType: key.ReportType}).Parse(j.config.Start)
+		if err != nil {
+			return err
+		}
+		syncer.Start = tr.Start
+	} else {
+		syncer.Start = LatestAvailable(key.ReportType, key.Frequency, d.Now())
+		cp, err := d.store.Load(key)
+		if err != nil {
+			return err
+		}
+		if cp == nil {
+			d.logger().Warn("stream has no start date, skipping its history", "job", j.config.Name,
+				"stream", key.String(), "start", syncer.Start.Format("2006-01-02"))
+			j.mu.Lock()
+			if !slices.Contains(j.status.WithoutHistory, key.String()) {
+				j.status.WithoutHistory = append(j.status.WithoutHistory, key.String())
+			}
+			j.mu.Unlock()
+		}
+	}
+
+	return syncer.SyncRaw(key, func(key SyncKey, period time.Time, raw *RawReport) error {
+		// stop between periods, so the checkpoint is kept
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, sink := range j.sinks {
+			if err := ExportRaw(sink, key, period, raw); err != nil {
+				return err
+			}
+		}
+		j.mu.Lock()
+		j.status.Periods++
+		j.status.Bytes += raw.Size
+		j.mu.Unlock()
+		return nil
+	})
+}
+
+func (d *Daemon) logger() *slog.Logger {
+	if d.Logger == nil {
+		return slog.Default()
+	}
+	return d.Logger
+}
+
+// Handler serves /healthz, failing once a job has failed Unhealthy times in a row, and
+// /metrics in the Prometheus text format
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", d.serveHealth)
+	mux.HandleFunc("GET /metrics", d.serveMetrics)
+	return mux
+}
+
+func (d *Daemon) serveHealth(w http.ResponseWriter, r *http.Request) {
+	jobs := d.Jobs()
+	status, code := "ok", http.StatusOK
+	if slices.ContainsFunc(jobs, func(j JobStatus) bool { return j.Failures >= d.Unhealthy }) {
+		status, code = "failing", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{"status": status, "jobs": jobs})
+}
+
+func (d *Daemon) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	jobs := d.Jobs()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metric := func(name string, kind string, help string, value func(JobStatus) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, j := range jobs {
+			fmt.Fprintf(w, "%s{job=\"%s\"} %g\n", name, metricLabel(j.Name), value(j))
+		}
+	}
+	unix := func(t time.Time) float64 {
+		if t.IsZero() {
+			return 0
+		}
+		return float64(t.UnixMilli()) / 1000
+	}
+
+	fmt.Fprintf(w, "# HELP asc_job_runs_total Runs of each job by result.\n# TYPE asc_job_runs_total counter\n")
+	for _, j := range jobs {
+		fmt.Fprintf(w, "asc_job_runs_total{job=\"%s\",result=\"success\"} %d\n", metricLabel(j.Name), j.runs-j.errors)
+		fmt.Fprintf(w, "asc_job_runs_total{job=\"%s\",result=\"error\"} %d\n", metricLabel(j.Name), j.errors)
+	}
+	metric("asc_job_consecutive_failures", "gauge", "Runs of each job which failed since its last success.",
+		func(j JobStatus) float64 { return float64(j.Failures) })
+	metric("asc_job_last_success_timestamp_seconds", "gauge", "When each job last succeeded.",
+		func(j JobStatus) float64 { return unix(j.LastSuccess) })
+	metric("asc_job_next_run_timestamp_seconds", "gauge", "When each job runs next.",
+		func(j JobStatus) float64 { return unix(j.NextRun) })
+	metric("asc_job_last_duration_seconds", "gauge", "How long the last run of each job took.",
+		func(j JobStatus) float64 { return j.duration.Seconds() })
+	metric("asc_job_periods_total", "counter", "Report periods exported by each job.",
+		func(j JobStatus) float64 { return float64(j.Periods) })
+	metric("asc_job_bytes_total", "counter", "Bytes of report files exported by each job.",
+		func(j JobStatus) float64 { return float64(j.Bytes) })
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func metricLabel(s string) string {
+	return metricLabelEscaper.Replace(s)
+}
@@ -0,0 +1,269 @@
+package appstoreconnect_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+// clock is the clock of newServerClient, in the evening of july 31st in Pacific Time
+func clock() time.Time {
+	return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestLoadDaemonConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemon.yml")
+	yml := "jobs:\n" +
+		"  - name: sales\n" +
+		"    frequencies: [DAILY, WEEKLY]\n" +
+		"    sinks: [archive, s3://bucket/apple]\n"
+	if err := os.WriteFile(path, []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := appstoreconnect.LoadDaemonConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Listen != ":9090" || config.Credentials != filepath.Join(dir, "credentials.yml") ||
+		config.Checkpoints != filepath.Join(dir, "checkpoints.json") || len(config.Jobs) != 1 {
+		t.Errorf("unexpected config %+v", config)
+	}
+	if sinks := config.Jobs[0].Sinks; sinks[0] != filepath.Join(dir, "archive") || sinks[1] != "s3://bucket/apple" {
+		t.Error("unexpected sinks", sinks)
+	}
+
+	if err := os.WriteFile(path, []byte("jobs:\n  - nme: sales\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appstoreconnect.LoadDaemonConfig(path); err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+}
+
+func TestDaemonRunJob(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddGeneratedSales(appstoreconnecttest.NewGenerator(1), mustRange(t, "2024-07-29:2024-07-31"))
+
+	dir := t.TempDir()
+	d, err := appstoreconnect.NewDaemon(client,
+		appstoreconnect.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json")),
+		&appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{{
+			Name:  "sales",
+			Start: "2024-07-29",
+			Sinks: []string{filepath.Join(dir, "archive")},
+		}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Now = clock
+	d.Logger = slog.New(slog.DiscardHandler)
+
+	if err := d.RunJob(context.Background(), "sales"); err != nil {
+		t.Fatal(err)
+	}
+	// the 31st is not published until 08:00 PDT
+	for _, day := range []string{"29", "30"} {
+		if _, err := os.Stat(filepath.Join(dir, "archive", "80000000/SALES/DAILY/2024/07", day+".tsv.gz.json")); err != nil {
+			t.Error("expected the period to be exported", day, err)
+		}
+	}
+
+	// the next run only fetches the periods apple may restate
+	requests := len(server.Requests())
+	if err := d.RunJob(context.Background(), "sales"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests()) - requests; n != 3 {
+		t.Error("expected only the restate window to be fetched", n)
+	}
+
+	status := d.Jobs()[0]
+	if status.Periods != 4 || status.Failures != 0 || status.LastSuccess.IsZero() || status.Bytes == 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if err := d.RunJob(context.Background(), "finance"); !errors.Is(err, appstoreconnect.ErrNoSuchJob) {
+		t.Error("expected an unknown job to fail")
+	}
+}
+
+func TestDaemonWithoutHistory(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddGeneratedSales(appstoreconnecttest.NewGenerator(1), mustRange(t, "2024-07-28:2024-07-30"))
+
+	dir := t.TempDir()
+	d, err := appstoreconnect.NewDaemon(client,
+		appstoreconnect.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json")),
+		&appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{{
+			Name:  "sales",
+			Sinks: []string{filepath.Join(dir, "archive")},
+		}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Now = clock
+	d.Logger = slog.New(slog.DiscardHandler)
+
+	if err := d.RunJob(context.Background(), "sales"); err != nil {
+		t.Fatal(err)
+	}
+	// only the latest published day is exported
+	if _, err := os.Stat(filepath.Join(dir, "archive", "80000000/SALES/DAILY/2024/07/29.tsv.gz.json")); err == nil {
+		t.Error("expected no history to be exported")
+	}
+	if err := d.RunJob(context.Background(), "sales"); err != nil {
+		t.Fatal(err)
+	}
+	status := d.Jobs()[0]
+	if len(status.WithoutHistory) != 1 || status.WithoutHistory[0] != "80000000/SALES/SUMMARY/DAILY/" {
+		t.Error("expected the stream to be listed once", status.WithoutHistory)
+	}
+}
+
+func TestDaemonHandler(t *testing.T) {
+	server, client := newServerClient(t)
+	dir := t.TempDir()
+	d, err := appstoreconnect.NewDaemon(client,
+		appstoreconnect.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json")),
+		&appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{{
+			Name:     "sales",
+			Start:    "2024-07-30",
+			Schedule: "0 9 * * *",
+			Sinks:    []string{dir},
+		}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Now = clock
+	d.Logger = slog.New(slog.DiscardHandler)
+	d.Unhealthy = 2
+	h := d.Handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	server.Fail(http.StatusUnauthorized, 2)
+	d.RunJob(context.Background(), "sales")
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Error("expected a single failure to be healthy", w.Code)
+	}
+	d.RunJob(context.Background(), "sales")
+	w := get("/healthz")
+	health := struct {
+		Status string                      `json:"status"`
+		Jobs   []appstoreconnect.JobStatus `json:"jobs"`
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusServiceUnavailable || health.Status != "failing" ||
+		health.Jobs[0].Failures != 2 || !strings.Contains(health.Jobs[0].LastError, "401") {
+		t.Errorf("expected repeated failures to be unhealthy %d %+v", w.Code, health)
+	}
+
+	// recovers once a run succeeds
+	if err := d.RunJob(context.Background(), "sales"); err != nil {
+		t.Fatal(err)
+	}
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Error("expected a success to be healthy again", w.Code)
+	}
+
+	metrics := get("/metrics").Body.String()
+	for _, line := range []string{
+		"# TYPE asc_job_runs_total counter\n",
+		`asc_job_runs_total{job="sales",result="success"} 1` + "\n",
+		`asc_job_runs_total{job="sales",result="error"} 2` + "\n",
+		`asc_job_consecutive_failures{job="sales"} 0` + "\n",
+		`asc_job_last_success_timestamp_seconds{job="sales"} 1.7224704e+09` + "\n",
+	} {
+		if !strings.Contains(metrics, line) {
+			t.Error("expected metric", line, metrics)
+		}
+	}
+}
+
+func TestDaemonRun(t *testing.T) {
+	_, client := newServerClient(t)
+	dir := t.TempDir()
+	d, err := appstoreconnect.NewDaemon(client,
+		appstoreconnect.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json")),
+		&appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{{Name: "sales", Sinks: []string{dir}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Now = clock
+	d.Logger = slog.New(slog.DiscardHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	// runs straight away, then waits for apple to publish the next report
+	deadline := time.After(5 * time.Second)
+	for d.Jobs()[0].NextRun.IsZero() {
+		select {
+		case <-deadline:
+			t.Fatal("expected the job to run")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if status := d.Jobs()[0]; status.LastRun.IsZero() || !status.NextRun.After(status.LastRun) {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected run to stop")
+	}
+
+	if _, err := appstoreconnect.NewDaemon(client, nil, &appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{
+		{Name: "sales", Sinks: []string{dir}, Schedule: "every day"},
+	}}); !errors.Is(err, appstoreconnect.ErrDaemonConfig) || !errors.Is(err, appstoreconnect.ErrScheduleInvalid) {
+		t.Error("expected an invalid schedule to fail", err)
+	}
+}
+
+func TestDaemonSharedStreams(t *testing.T) {
+	_, client := newServerClient(t)
+	dir := t.TempDir()
+
+	// each stream has one checkpoint, so two jobs syncing it would not both export every period
+	_, err := appstoreconnect.NewDaemon(client, nil, &appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{
+		{Name: "sales", Frequencies: []appstoreconnect.Frequency{appstoreconnect.Daily, appstoreconnect.Weekly}, Sinks: []string{filepath.Join(dir, "archive")}},
+		{Name: "sales-archive", Sinks: []string{dir}},
+	}})
+	if !errors.Is(err, appstoreconnect.ErrDaemonConfig) || !strings.Contains(err.Error(), "sales-archive") {
+		t.Error("expected jobs sharing a stream to be rejected", err)
+	}
+
+	// other vendors or frequencies are separate streams
+	if _, err := appstoreconnect.NewDaemon(client, nil, &appstoreconnect.DaemonConfig{Jobs: []*appstoreconnect.JobConfig{
+		{Name: "sales", Frequencies: []appstoreconnect.Frequency{appstoreconnect.Weekly}, Sinks: []string{dir}},
+		{Name: "sales-daily", Sinks: []string{dir}},
+		{Name: "other", Vendors: []string{"80000001"}, Sinks: []string{dir}},
+	}}); err != nil {
+		t.Error(err)
+	}
+}
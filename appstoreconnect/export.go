@@ -0,0 +1,216 @@
+package appstoreconnect
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exporting archives apple's report files to a Sink, one object per period at a path derived
+// from the report alone, like 80000000/SALES/DAILY/2024/05/01.tsv.gz, each next to a .json
+// sidecar describing it. Periods already exported are skipped, so an export can be rerun or
+// resumed at any time.
+
+// Sink stores exported report files
+type Sink interface {
+	// Exists reports whether an object has been written to path
+	Exists(path string) (bool, error)
+	// Write stores body at path, replacing anything there
+	Write(path string, body []byte) error
+}
+
+// ExportMetadata is the sidecar written next to each exported report
+type ExportMetadata struct {
+	*RawReport
+	VendorNumber string            `json:"vendor_number"`
+	Filters      map[string]string `json:"filters"`
+	PeriodStart  string            `json:"period_start"`
+	PeriodEnd    string            `json:"period_end"`
+}
+
+// ExportStats counts what an export did
+type ExportStats struct {
+	// Written periods were fetched and written to the sink
+	Written int
+	// Skipped periods were already in the sink
+	Skipped int
+	// Empty periods had no data
+	Empty int
+}
+
+// Exporter copies reports from a client to a sink
+type Exporter struct {
+	client *Client
+	sink   Sink
+
+	// Overwrite writes periods again even if they are already in the sink
+	Overwrite bool
+}
+
+// NewExporter creates an exporter of the reports of client
+func NewExporter(client *Client, sink Sink) *Exporter {
+	return &Exporter{client: client, sink: sink}
+}
+
+// ExportSales exports a sales report for each period of tr. A period apple has not published
+// yet stops the export with ErrNotYetPublished.
+func (e *Exporter) ExportSales(tr *TimeRange, reportType ReportType, reportSubType ReportSubType) (ExportStats, error) {
+	return e.export(tr,
+		func(p Period) string {
+			return ExportPath(e.client.vendorNumber, reportType, reportSubType, "", p)
+		},
+		func(p Period) (*RawReport, error) {
+			_, raw, err := e.client.SalesReport.GetRaw(p.Date, p.Frequency, reportType, reportSubType)
+			return raw, err
+		})
+}
+
+// ExportFinance exports a finance report for each fiscal period covering tr
+func (e *Exporter) ExportFinance(tr *TimeRange, regionCode string) (ExportStats, error) {
+	return e.export(tr.Fiscal(AppleFiscalCalendar),
+		func(p Period) string {
+			return ExportPath(e.client.vendorNumber, ReportFinancial, "", regionCode, p)
+		},
+		func(p Period) (*RawReport, error) {
+			_, raw, err := e.client.FinanceReport.GetRaw(p.Date, regionCode)
+			return raw, err
+		})
+}
+
+// export writes each period of tr not yet in the sink to pathOf(period), and its metadata
+// after it, so a period is only complete once its sidecar exists
+func (e *Exporter) export(tr *TimeRange, pathOf func(Period) string, fetch func(Period) (*RawReport, error)) (ExportStats, error) {
+	stats := ExportStats{}
+	for p := range tr.Periods() {
+		name := pathOf(p)
+		if !e.Overwrite {
+			done, err := e.sink.Exists(name + ".json")
+			if err != nil {
+				return stats, err
+			}
+			if done {
+				stats.Skipped++
+				continue
+			}
+		}
+
+		raw, err := fetch(p)
+		if err == ErrNoData {
+			stats.Empty++
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		if err := writeExport(e.sink, name, raw, p); err != nil {
+			return stats, err
+		}
+		stats.Written++
+	}
+	return stats, nil
+}
+
+// ExportRaw writes a report file fetched for the period of key containing date to its
+// ExportPath in sink, replacing any earlier export, e.g. from a Syncer's RawSyncFunc
+func ExportRaw(sink Sink, key SyncKey, date time.Time, raw *RawReport) error {
+	f := key.Frequency
+	if key.ReportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+	p := NewPeriod(date, f)
+	name := ExportPath(key.VendorNumber, key.ReportType, key.ReportSubType, key.RegionCode, p)
+	return writeExport(sink, name, raw, p)
+}
+
+// writeExport writes raw to name and its metadata after it
+func writeExport(sink Sink, name string, raw *RawReport, p Period) error {
+	meta, err := exportMetadata(raw, p)
+	if err != nil {
+		return err
+	}
+	if err := sink.Write(name, raw.Body); err != nil {
+		return err
+	}
+	return sink.Write(name+".json", meta)
+}
+
+// ExportPath is where a period of a report is exported to, like 80000000/SALES/DAILY/2024/05/01.tsv.gz.
+// Sub types other than summary are appended to the report type, and finance reports are exported
+// by region, like 80000000/FINANCIAL/US/2024/05.tsv.gz.
+func ExportPath(vendorNumber string, reportType ReportType, reportSubType ReportSubType, regionCode string, p Period) string {
+	kind := string(reportType)
+	if reportSubType != "" && reportSubType != SubReportSummary {
+		kind += "_" + string(reportSubType)
+	}
+
+	var date string
+	switch p.Frequency {
+	case Yearly:
+		date = p.Date.Format("2006")
+	case Monthly, FiscalMonthly:
+		date = p.Date.Format("2006/01")
+	default:
+		date = p.Date.Format("2006/01/02")
+	}
+
+	group := string(p.Frequency)
+	if reportType == ReportFinancial {
+		group = regionCode
+	}
+	return path.Join(vendorNumber, kind, group, date) + ".tsv.gz"
+}
+
+// DirSink exports to a local directory
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink creates a sink writing below dir
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// Exists reports whether a file has been written to path
+func (d *DirSink) Exists(path string) (bool, error) {
+	_, err := os.Stat(d.file(path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Write atomically writes body to path, creating directories as needed
+func (d *DirSink) Write(path string, body []byte) error {
+	file := d.file(path)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, body)
+}
+
+func (d *DirSink) file(path string) string {
+	return filepath.Join(d.dir, filepath.FromSlash(strings.TrimPrefix(path, "/")))
+}
+
+// NewSink creates a sink from a url, s3://bucket/prefix for an S3 compatible bucket or a
+// local directory
+func NewSink(u string) (Sink, error) {
+	if strings.HasPrefix(u, "s3://") {
+		return NewS3SinkFromEnv(u)
+	}
+	return NewDirSink(strings.TrimPrefix(u, "file://")), nil
+}
+
+func exportMetadata(raw *RawReport, p Period) ([]byte, error) {
+	return json.MarshalIndent(ExportMetadata{
+		RawReport:    raw,
+		VendorNumber: raw.Operation.VendorNumber,
+		Filters:      raw.Operation.Filters,
+		PeriodStart:  p.Start.Format("2006-01-02"),
+		PeriodEnd:    p.End.Format("2006-01-02"),
+	}, "", "  ")
+}
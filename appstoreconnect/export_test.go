@@ -0,0 +1,72 @@
+package appstoreconnect_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+func TestExportS3(t *testing.T) {
+	server, client := newServerClient(t)
+	tr := mustRange(t, "2024-05-01:2024-05-03")
+	server.AddGeneratedSales(appstoreconnecttest.NewGenerator(1), mustRange(t, "2024-05-01:2024-05-02"))
+
+	s3 := appstoreconnecttest.NewS3Server()
+	defer s3.Close()
+	exporter := appstoreconnect.NewExporter(client, s3.Sink("reports", "apple"))
+
+	stats, err := exporter.ExportSales(tr, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (appstoreconnect.ExportStats{Written: 2, Empty: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	objects := s3.Objects()
+	body, ok := objects["reports/apple/80000000/SALES/DAILY/2024/05/01.tsv.gz"]
+	if !ok || body[0] != 0x1f {
+		t.Fatal("expected the gzipped report", len(objects))
+	}
+	meta := appstoreconnect.ExportMetadata{}
+	if err := json.Unmarshal(objects["reports/apple/80000000/SALES/DAILY/2024/05/01.tsv.gz.json"], &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.RawReport == nil || meta.SHA256 == "" || meta.Size != len(body) || meta.PeriodStart != "2024-05-01" ||
+		meta.Filters["filter[frequency]"] != "DAILY" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	// exported periods are skipped, the empty one is tried again
+	stats, err = exporter.ExportSales(tr, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (appstoreconnect.ExportStats{Skipped: 2, Empty: 1}) || s3.Puts() != 4 {
+		t.Errorf("unexpected stats %+v after %d puts", stats, s3.Puts())
+	}
+}
+
+func TestExportDir(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddFinanceReport("2024-05", "US", financeTsv)
+
+	dir := t.TempDir()
+	exporter := appstoreconnect.NewExporter(client, appstoreconnect.NewDirSink(dir))
+	stats, err := exporter.ExportFinance(mustRange(t, "2024-05"), "US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Written != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	for _, name := range []string{"05.tsv.gz", "05.tsv.gz.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "80000000", "FINANCIAL", "US", "2024", name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"io"
 	"reflect"
 	"strings"
 	"time"
@@ -26,32 +27,38 @@ type FinanceReport service
 // FinanceReportResponse represents the response from a finance report request
 type FinanceReportResponse struct {
 	Reports []*FinanceReportItem
+
+	// vendors is set on responses merging several vendors, whose csv and tsv end with a
+	// Vendor Number column
+	vendors bool
 }
 
 // FinanceReportItem is information returned about FINANCIAL reports
 type FinanceReportItem struct {
-	StartDate                        string  `tsv:"Start Date"`
-	EndDate                          string  `tsv:"End Date"`
-	UPC                              string  `tsv:"UPC"`
-	ISRCISBN                         string  `tsv:"ISRC/ISBN"`
-	VendorIdentifier                 string  `tsv:"Vendor Identifier"`
-	Quantity                         string  `tsv:"Quantity"`
-	PartnerShare                     string  `tsv:"Partner Share"`
-	ExtendedPartnerShare             string  `tsv:"Extended Partner Share"`
-	PartnerShareCurrency             string  `tsv:"Partner Share Currency"`
-	SalesOrReturn                    string  `tsv:"Sales or Return"`
-	AppleIdentifier                  string  `tsv:"Apple Identifier"`
-	ArtistShowDeveloperAuthor        string  `tsv:"Artist/Show/Developer/Author"`
-	Title                            string  `tsv:"Title"`
+	StartDate                            string `tsv:"Start Date"`
+	EndDate                              string `tsv:"End Date"`
+	UPC                                  string `tsv:"UPC"`
+	ISRCISBN                             string `tsv:"ISRC/ISBN"`
+	VendorIdentifier                     string `tsv:"Vendor Identifier"`
+	Quantity                             string `tsv:"Quantity"`
+	PartnerShare                         string `tsv:"Partner Share"`
+	ExtendedPartnerShare                 string `tsv:"Extended Partner Share"`
+	PartnerShareCurrency                 string `tsv:"Partner Share Currency"`
+	SalesOrReturn                        string `tsv:"Sales or Return"`
+	AppleIdentifier                      string `tsv:"Apple Identifier"`
+	ArtistShowDeveloperAuthor            string `tsv:"Artist/Show/Developer/Author"`
+	Title                                string `tsv:"Title"`
 	LabelStudioNetworkDeveloperPublisher string `tsv:"Label/Studio/Network/Developer/Publisher"`
-	Grid                             string  `tsv:"Grid"`
-	ProductTypeIdentifier            string  `tsv:"Product Type Identifier"`
-	ISANOtherIdentifier              string  `tsv:"ISAN/Other Identifier"`
-	CountryOfSale                    string  `tsv:"Country Of Sale"`
-	PreOrderFlag                     string  `tsv:"Pre-order Flag"`
-	PromoCode                        string  `tsv:"Promo Code"`
-	CustomerPrice                    string  `tsv:"Customer Price"`
-	CustomerCurrency                 string  `tsv:"Customer Currency"`
+	Grid                                 string `tsv:"Grid"`
+	ProductTypeIdentifier                string `tsv:"Product Type Identifier"`
+	ISANOtherIdentifier                  string `tsv:"ISAN/Other Identifier"`
+	CountryOfSale                        string `tsv:"Country Of Sale"`
+	PreOrderFlag                         string `tsv:"Pre-order Flag"`
+	PromoCode                            string `tsv:"Promo Code"`
+	CustomerPrice                        string `tsv:"Customer Price"`
+	CustomerCurrency                     string `tsv:"Customer Currency"`
+	// VendorNumber is the vendor the row was fetched for, not part of apple's report
+	VendorNumber string `tsv:"-"`
 }
 
 // Clone deep copy
@@ -64,8 +71,9 @@ func (f *FinanceReportItem) GetHeader() []string {
 	t := reflect.TypeFor[FinanceReportItem]()
 	tags := []string{}
 	for field := range t.Fields() {
-		tag := field.Tag.Get("tsv")
-		tags = append(tags, tag)
+		if tag := field.Tag.Get("tsv"); tag != "-" {
+			tags = append(tags, tag)
+		}
 	}
 	return tags
 }
@@ -74,22 +82,61 @@ func (f *FinanceReportItem) Values() []string {
 	vals := []string{}
 	t := reflect.ValueOf(f).Elem()
 	for i := range t.NumField() {
-		vals = append(vals, t.Field(i).String())
+		if t.Type().Field(i).Tag.Get("tsv") != "-" {
+			vals = append(vals, t.Field(i).String())
+		}
 	}
 	return vals
 }
 
 // Get fetches a finance report for a given month and region code.
-// Finance reports are always MONTHLY, so only the year and month of date are used.
+// Finance reports cover Apple fiscal periods, named by calendar month, so only the year and month
+// of date are used to pick the period named after that month. Use GetPeriod for the period
+// containing a particular day.
 // regionCode is the two-letter region code (e.g. "US", "ZZ" for worldwide).
 func (f *FinanceReport) Get(date time.Time, regionCode string) (*FinanceReportResponse, error) {
-	b, err := f.client.get(pathFinanceReports,
-		map[string]string{
+	ret, _, err := f.GetRaw(date, regionCode)
+	return ret, err
+}
+
+// GetRaw is Get also returning the report file exactly as apple served it
+func (f *FinanceReport) GetRaw(date time.Time, regionCode string) (*FinanceReportResponse, *RawReport, error) {
+	raw, err := f.client.getRaw(&Operation{
+		Name: "FinanceReport.Get",
+		Path: pathFinanceReports,
+		Filters: map[string]string{
 			"filter[regionCode]": regionCode,
 			"filter[reportDate]": timeToReportDate(date, Monthly),
-			"filter[reportType]": "FINANCIAL",
+			"filter[reportType]": string(ReportFinancial),
 		},
-	)
+		Period: NewPeriod(date, FiscalMonthly),
+	})
+	if err == ErrNoData && !IsPublished(ReportFinancial, date, FiscalMonthly, f.client.clock()) {
+		return nil, nil, ErrNotYetPublished
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ret, err := ParseFinanceReport(bytes.NewReader(raw.Body))
+	if err != nil {
+		return nil, raw, err
+	}
+	for _, r := range ret.Reports {
+		r.VendorNumber = f.client.vendorNumber
+	}
+	return ret, raw, nil
+}
+
+// ParseFinanceReport decodes a finance report, such as one downloaded from the App Store Connect
+// website. The input may be gzipped and may include Apple's trailing total rows.
+func ParseFinanceReport(r io.Reader) (*FinanceReportResponse, error) {
+	rc, err := maybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(stripBOM(rc))
 	if err != nil {
 		return nil, err
 	}
@@ -114,12 +161,23 @@ func (f *FinanceReport) Get(date time.Time, regionCode string) (*FinanceReportRe
 	return &ret, nil
 }
 
-// GetRange fetches finance reports for every month in the given TimeRange.
-// ErrNoData months are silently skipped.
+// GetPeriod fetches the finance report for a fiscal period
+func (f *FinanceReport) GetPeriod(p FiscalPeriod, regionCode string) (*FinanceReportResponse, error) {
+	return f.Get(p.Month(), regionCode)
+}
+
+// GetRange fetches finance reports for every fiscal period in the given TimeRange.
+// Daily and weekly ranges fetch the periods containing their days, yearly ranges whole fiscal years.
+// ErrNoData months are silently skipped, a month not yet published stops the range with
+// ErrNotYetPublished and the months fetched so far.
 func (f *FinanceReport) GetRange(tr *TimeRange, regionCode string) (*FinanceReportResponse, error) {
+	if tr.Frequency != Monthly && tr.Frequency != FiscalMonthly {
+		tr = tr.Fiscal(AppleFiscalCalendar)
+	}
+
 	ret := FinanceReportResponse{}
-	for tr.Next() {
-		r, err := f.Get(tr.Current(), regionCode)
+	for p := range tr.Periods() {
+		r, err := f.Get(p.Date, regionCode)
 		if err != nil {
 			if err == ErrNoData {
 				continue
@@ -131,6 +189,21 @@ func (f *FinanceReport) GetRange(tr *TimeRange, regionCode string) (*FinanceRepo
 	return &ret, nil
 }
 
+// GetVendors fetches finance reports for the TimeRange from each vendor concurrently. Rows are
+// tagged with the vendor they came from, which the csv and tsv add as a Vendor Number column,
+// and merged in the order of vendors.
+func (f *FinanceReport) GetVendors(vendors []string, tr *TimeRange, regionCode string) (*FinanceReportResponse, error) {
+	responses, err := fanOut(vendors, func(vendor string) (*FinanceReportResponse, error) {
+		return f.client.WithVendor(vendor).FinanceReport.GetRange(tr, regionCode)
+	})
+
+	ret := FinanceReportResponse{vendors: true}
+	for _, r := range responses {
+		ret.Reports = append(ret.Reports, r.Reports...)
+	}
+	return &ret, err
+}
+
 // stripFinanceFooter removes Apple's trailing summary rows (Total_Rows, Total_Amount,
 // Total_Units) which have fewer fields than the header and break the csv.Reader.
 func stripFinanceFooter(b []byte) []byte {
@@ -158,12 +231,18 @@ func (f *FinanceReportResponse) ToCsv() ([]byte, error) {
 	w := csv.NewWriter(buf)
 	d := FinanceReportItem{}
 	headers := d.GetHeader()
+	if f.vendors {
+		headers = append(headers, vendorNumberHeader)
+	}
 	if err := w.Write(headers); err != nil {
 		return nil, err
 	}
 
 	for _, r := range f.Reports {
 		values := r.Values()
+		if f.vendors {
+			values = append(values, r.VendorNumber)
+		}
 		if err := w.Write(values); err != nil {
 			return nil, err
 		}
@@ -180,12 +259,18 @@ func (f *FinanceReportResponse) ToTsv() ([]byte, error) {
 	w.Comma = '\t'
 	d := FinanceReportItem{}
 	headers := d.GetHeader()
+	if f.vendors {
+		headers = append(headers, vendorNumberHeader)
+	}
 	if err := w.Write(headers); err != nil {
 		return nil, err
 	}
 
 	for _, r := range f.Reports {
 		values := r.Values()
+		if f.vendors {
+			values = append(values, r.VendorNumber)
+		}
 		if err := w.Write(values); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,131 @@
+package appstoreconnect
+
+import (
+	"time"
+)
+
+// Apple pays and reports finances by fiscal period rather than calendar month. The fiscal year
+// ends on the last Saturday of September and is split into twelve periods of whole weeks, each
+// named after the calendar month it mostly covers.
+// https://developer.apple.com/app-store-connect/fiscal-calendar
+
+// FiscalMonthly is the frequency of a TimeRange over fiscal periods. Each period is identified
+// by the first day of the calendar month it is named after, which is what the finance reports
+// endpoint expects.
+const FiscalMonthly Frequency = "FISCAL_MONTHLY"
+
+// FiscalCalendar describes a 52/53 week retail calendar ending on the last Saturday of September
+type FiscalCalendar struct {
+	// Pattern is the number of weeks in each period of a quarter
+	Pattern [3]int
+}
+
+// FiscalPeriod is one period of a fiscal year
+type FiscalPeriod struct {
+	// Year is the fiscal year, which begins in the previous calendar year
+	Year int
+	// Period is 1 through 12, period 1 is October
+	Period int
+	// Start is the first day of the period, a sunday
+	Start time.Time
+	// End is the last day of the period, a saturday
+	End time.Time
+}
+
+// AppleFiscalCalendar is the calendar Apple publishes for payments and financial reports, with
+// five weeks in the first period of each quarter. In 53 week years the extra week falls in
+// period 1.
+var AppleFiscalCalendar = FiscalCalendar{Pattern: [3]int{5, 4, 4}}
+
+// YearEnd is the last day of fiscal year year
+func (c FiscalCalendar) YearEnd(year int) time.Time {
+	t := time.Date(year, time.September, 30, 0, 0, 0, 0, time.UTC)
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 1) % 7))
+}
+
+// YearStart is the first day of fiscal year year
+func (c FiscalCalendar) YearStart(year int) time.Time {
+	return c.YearEnd(year-1).AddDate(0, 0, 1)
+}
+
+// Periods lists the twelve periods of fiscal year year
+func (c FiscalCalendar) Periods(year int) []FiscalPeriod {
+	start := c.YearStart(year)
+	weeks := (int(c.YearEnd(year).Sub(start).Hours()/24) + 1) / 7
+
+	periods := make([]FiscalPeriod, 0, 12)
+	for i := range 12 {
+		n := c.Pattern[i%3]
+		if i == 0 && weeks == 53 {
+			n++
+		}
+		end := start.AddDate(0, 0, n*7)
+		periods = append(periods, FiscalPeriod{
+			Year:   year,
+			Period: i + 1,
+			Start:  start,
+			End:    end.AddDate(0, 0, -1),
+		})
+		start = end
+	}
+	return periods
+}
+
+// PeriodOf returns the fiscal period containing the day t falls on
+func (c FiscalCalendar) PeriodOf(t time.Time) FiscalPeriod {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	year := t.Year()
+	if day.After(c.YearEnd(year)) {
+		year++
+	}
+	for _, p := range c.Periods(year) {
+		if !day.After(p.End) {
+			return p
+		}
+	}
+	// unreachable, the periods cover the whole year
+	return FiscalPeriod{}
+}
+
+// PeriodNamed returns the fiscal period named after the calendar month containing month
+func (c FiscalCalendar) PeriodNamed(month time.Time) FiscalPeriod {
+	year := month.Year()
+	if month.Month() >= time.October {
+		year++
+	}
+	return c.Periods(year)[(int(month.Month())+2)%12]
+}
+
+// Month returns the first day of the calendar month the period is named after
+func (p FiscalPeriod) Month() time.Time {
+	m := time.Month((p.Period+8)%12 + 1)
+	year := p.Year
+	if m >= time.October {
+		year--
+	}
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Contains reports whether the day t falls on is in the period
+func (p FiscalPeriod) Contains(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(p.Start) && !day.After(p.End)
+}
+
+// Fiscal returns a range over the fiscal periods covering t. Monthly ranges already name fiscal
+// periods by month, yearly ranges are taken as fiscal years, and days and weeks are mapped to
+// the period containing them.
+func (t *TimeRange) Fiscal(c FiscalCalendar) *TimeRange {
+	var start, end time.Time
+	switch t.Frequency {
+	case FiscalMonthly, Monthly:
+		start, end = t.Start, t.End
+	case Yearly:
+		start = c.Periods(t.Start.Year())[0].Month()
+		end = c.Periods(t.End.Year())[11].Month()
+	default:
+		start = c.PeriodOf(t.Start).Month()
+		end = c.PeriodOf(t.End).Month()
+	}
+	return NewTimeRange(start, end, FiscalMonthly)
+}
@@ -0,0 +1,17 @@
+package appstoreconnect
+
+// Report files shared by the tests of this package and, through the exported names, of
+// appstoreconnect_test
+
+const salesTsv = "Provider\tProvider Country\tSKU\tDeveloper\tTitle\tVersion\tProduct Type Identifier\tUnits\tDeveloper Proceeds\tBegin Date\tEnd Date\tCustomer Currency\tCountry Code\tCurrency of Proceeds\tApple Identifier\tCustomer Price\n" +
+	"APPLE\tUS\tcom.example.app\tExample\tExample App\t1.0\t1F\t3\t0.70\t09/08/2019\t09/08/2019\tUSD\tUS\tUSD\t123456\t0.99\n"
+
+const financeTsv = "Start Date\tEnd Date\tUPC\tISRC/ISBN\tVendor Identifier\tQuantity\tPartner Share\tExtended Partner Share\tPartner Share Currency\tSales or Return\tApple Identifier\n" +
+	"09/01/2019\t09/28/2019\t\t\tcom.example.app\t3\t0.70\t2.10\tUSD\tS\t123456\n" +
+	"Total_Rows\t1\n" +
+	"Total_Amount\t2.10\n"
+
+const (
+	SalesTsv   = salesTsv
+	FinanceTsv = financeTsv
+)
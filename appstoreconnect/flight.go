@@ -0,0 +1,51 @@
+package appstoreconnect
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// flightGroup makes one call at a time per key, handing its result to every caller arriving
+// while it runs
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done   chan struct{}
+	report encoding.Encodable
+	err    error
+}
+
+// do returns the result of fn, or of the call of fn for key already in flight. A panic in fn is
+// returned to every caller as an error.
+func (g *flightGroup) do(key string, fn func() (encoding.Encodable, error)) (report encoding.Encodable, err error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.report, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			f.report, f.err = nil, fmt.Errorf("fetching %s panicked: %v", key, r)
+		}
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+		report, err = f.report, f.err
+	}()
+	f.report, f.err = fn()
+	return f.report, f.err
+}
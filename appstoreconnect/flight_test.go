@@ -0,0 +1,81 @@
+package appstoreconnect
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+func TestFlightGroup(t *testing.T) {
+	g := flightGroup{}
+	report := &SalesReportResponse{Reports: []*SalesReportItem{{SKU: "com.example.app"}}}
+	var calls atomic.Int32
+	var started sync.WaitGroup
+	fetch := func() (encoding.Encodable, error) {
+		if calls.Add(1) == 1 {
+			// give every caller time to join the first one's call
+			started.Wait()
+			time.Sleep(20 * time.Millisecond)
+		}
+		return report, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]encoding.Encodable, 5)
+	started.Add(len(results))
+	for i := range results {
+		wg.Go(func() {
+			started.Done()
+			results[i], _ = g.do("sales", fetch)
+		})
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n < 1 || int(n) >= len(results) {
+		t.Error("expected callers to share a call", n)
+	}
+	for _, r := range results {
+		if r != report {
+			t.Error("expected every caller to get the report")
+		}
+	}
+
+	// done flights are forgotten, errors are shared too
+	failed := errors.New("failed")
+	if _, err := g.do("sales", func() (encoding.Encodable, error) { return nil, failed }); err != failed {
+		t.Error("expected a new call", err)
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	g := flightGroup{}
+	release := make(chan struct{})
+	joined := make(chan error)
+	go func() {
+		// waits for the panicking call below, or makes its own if it comes first
+		<-release
+		_, err := g.do("sales", func() (encoding.Encodable, error) { return nil, errors.New("not shared") })
+		joined <- err
+	}()
+
+	report, err := g.do("sales", func() (encoding.Encodable, error) {
+		close(release)
+		time.Sleep(10 * time.Millisecond)
+		panic("boom")
+	})
+	if report != nil || err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Error("expected the panic as an error", report, err)
+	}
+	if err := <-joined; err == nil {
+		t.Error("expected an error for the waiting caller")
+	}
+
+	if _, err := g.do("sales", func() (encoding.Encodable, error) { return nil, nil }); err != nil {
+		t.Error("expected the flight to be forgotten", err)
+	}
+}
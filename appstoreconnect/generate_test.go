@@ -0,0 +1,101 @@
+package appstoreconnect_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+func TestGenerator(t *testing.T) {
+	g := appstoreconnecttest.NewGenerator(42)
+	week := mustRange(t, "2024-05-01:2024-05-07")
+	day := mustRange(t, "2024-05-03")
+
+	sales := g.Sales(week)
+	again := appstoreconnecttest.NewGenerator(42).Sales(day)
+	var may3 []*appstoreconnect.SalesReportItem
+	for _, r := range sales.Reports {
+		if r.BeginDate == "05/03/2024" {
+			may3 = append(may3, r)
+		}
+	}
+	if len(may3) == 0 || !reflect.DeepEqual(may3, again.Reports) {
+		t.Error("expected a day to generate the same rows in any range")
+	}
+	if other := appstoreconnecttest.NewGenerator(43).Sales(day); reflect.DeepEqual(other, again) {
+		t.Error("expected another seed to generate other rows")
+	}
+
+	var refunds, promos int
+	for _, r := range sales.Reports {
+		if r.Units < 0 {
+			refunds++
+		}
+		if r.PromoCode != "" {
+			promos++
+		}
+	}
+	if refunds == 0 || promos == 0 {
+		t.Error("expected refunds and promo codes", refunds, promos)
+	}
+
+	for p := range day.Periods() {
+		parsed, err := appstoreconnect.ParseSalesReport(strings.NewReader(g.SalesTSV(p)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parsed.Reports, again.Reports) {
+			t.Error("expected the tsv to parse to the generated rows")
+		}
+	}
+}
+
+func TestGeneratedFinance(t *testing.T) {
+	g := appstoreconnecttest.NewGenerator(42)
+	tr := mustRange(t, "2024-05")
+	for p := range tr.Fiscal(appstoreconnect.AppleFiscalCalendar).Periods() {
+		tsv := g.FinanceTSV(p, "EU")
+		if !strings.Contains(tsv, "\nTotal_Rows\t") || !strings.Contains(tsv, "\nTotal_Amount\t") {
+			t.Error("expected footer totals", tsv)
+		}
+		parsed, err := appstoreconnect.ParseFinanceReport(strings.NewReader(tsv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed.Reports) == 0 || len(parsed.Reports) != len(g.FinancePeriod(p, "EU")) {
+			t.Error("unexpected rows", len(parsed.Reports))
+		}
+		for _, r := range parsed.Reports {
+			if r.PartnerShareCurrency != "EUR" || r.StartDate != "05/05/2024" {
+				t.Errorf("unexpected row %+v", r)
+			}
+		}
+	}
+}
+
+func TestServeGenerated(t *testing.T) {
+	server, client := newServerClient(t)
+	g := appstoreconnecttest.NewGenerator(7)
+	tr := mustRange(t, "2024-05-01:2024-05-03")
+	server.AddGeneratedSales(g, tr)
+
+	r, err := client.SalesReport.GetRange(tr, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Reports) != len(g.Sales(tr).Reports) {
+		t.Error("unexpected rows", len(r.Reports))
+	}
+}
+
+func mustRange(t *testing.T, s string) *appstoreconnect.TimeRange {
+	t.Helper()
+	tr, err := appstoreconnect.ParseTimeRange(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
@@ -0,0 +1,164 @@
+package appstoreconnect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Opt in structured logging of requests. Secrets never reach the log: the bearer token and the
+// private key are not logged, and the issuer id is redacted wherever credentials are formatted.
+
+// Redacted replaces secrets in logs, errors and formatted credentials
+const Redacted = "REDACTED"
+
+// WithLogger logs each request to apple with its operation, filters, status, duration, response
+// size and remaining rate limit
+func WithLogger(logger *slog.Logger) ClientOption {
+	return WithMiddleware(logging(logger))
+}
+
+func logging(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			attrs := []slog.Attr{slog.String("path", req.URL.Path)}
+			if op, ok := OperationFromContext(req.Context()); ok {
+				attrs = append(attrs,
+					slog.String("operation", op.Name),
+					slog.String("vendor", op.VendorNumber),
+					slog.Any("filters", op.Filters))
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				attrs = append(attrs, slog.Duration("duration", time.Since(start)), slog.Any("error", err))
+				logger.LogAttrs(req.Context(), slog.LevelError, "appstoreconnect request failed", attrs...)
+				return resp, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if rem, ok := rateLimitRemaining(resp.Header); ok {
+				attrs = append(attrs, slog.Int("rate_limit_remaining", rem))
+			}
+			level := slog.LevelInfo
+			if resp.StatusCode > 299 && resp.StatusCode != 404 {
+				level = slog.LevelWarn
+			}
+
+			// the body has not been read yet, log once it has
+			resp.Body = &loggedBody{ReadCloser: resp.Body, done: func(n int64) {
+				attrs = append(attrs, slog.Duration("duration", time.Since(start)), slog.Int64("bytes", n))
+				logger.LogAttrs(context.Background(), level, "appstoreconnect request", attrs...)
+			}}
+			return resp, nil
+		})
+	}
+}
+
+// loggedBody counts the bytes read and calls done when closed
+type loggedBody struct {
+	io.ReadCloser
+	n      int64
+	done   func(n int64)
+	closed bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.done(b.n)
+	}
+	return err
+}
+
+// rateLimitRemaining parses the hourly requests remaining from a header like
+// "X-Rate-Limit: user-hour-lim:3600;user-hour-rem:3599;"
+func rateLimitRemaining(h http.Header) (int, bool) {
+	for part := range strings.SplitSeq(h.Get("X-Rate-Limit"), ";") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(part), "user-hour-rem:"); ok {
+			n, err := strconv.Atoi(v)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// String formats the credentials without the private key or issuer id
+func (c Credentials) String() string {
+	return fmt.Sprintf("{KeyID:%s IssuerID:%s PrivKey:%s PrivKeyPath:%s VendorNumber:%s VendorNumbers:%v KeyType:%s Scope:%v}",
+		c.KeyID, redact(c.IssuerID), redact(c.PrivKey), c.PrivKeyPath, c.VendorNumber, c.VendorNumbers, c.KeyType, c.Scope)
+}
+
+// GoString formats the credentials for %#v without the private key or issuer id
+func (c Credentials) GoString() string {
+	return "appstoreconnect.Credentials" + c.String()
+}
+
+// LogValue logs the credentials without the private key or issuer id
+func (c Credentials) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("key_id", c.KeyID),
+		slog.String("issuer_id", redact(c.IssuerID)),
+		slog.String("private_key", redact(c.PrivKey)),
+		slog.String("private_key_path", c.PrivKeyPath),
+		slog.String("vendor_number", c.VendorNumber),
+		slog.String("key_type", string(c.KeyType)),
+	)
+}
+
+// redact hides a secret, leaving it visible whether it was set at all
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// redactError removes the current token and the issuer id from an error message
+func (s *tokenSource) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	s.mu.Lock()
+	secrets := []string{s.token, s.issuerID}
+	s.mu.Unlock()
+
+	redacted := msg
+	for _, secret := range secrets {
+		if secret != "" {
+			redacted = strings.ReplaceAll(redacted, secret, Redacted)
+		}
+	}
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+// redactedError keeps the original error for errors.Is and errors.As but not its message
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,61 @@
+package appstoreconnect
+
+import (
+	"context"
+	"net/http"
+)
+
+// Middleware wraps the transport requests to apple are sent with, to log, trace or meter them.
+// The Operation a request is made for is in its context, see OperationFromContext. Responses
+// served from the cache never reach the transport.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an http.RoundTripper function, like http.HandlerFunc
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Operation is the logical call a request is made for
+type Operation struct {
+	// Name is the method making the request, like "SalesReport.Get"
+	Name string
+	// Path is relative to the api, like "salesReports"
+	Path string
+	// Filters are the report filters as sent, like filter[reportDate], without the vendor number
+	Filters map[string]string
+	// Period is the report period requested
+	Period Period
+	// VendorNumber is the vendor the report is requested for
+	VendorNumber string
+}
+
+type operationKey struct{}
+
+// OperationFromContext returns the operation a request is made for
+func OperationFromContext(ctx context.Context) (*Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(*Operation)
+	return op, ok
+}
+
+func withOperation(ctx context.Context, op *Operation) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+// WithMiddleware wraps the transport in middleware. The first middleware sees a request first
+// and its response last, and middleware from earlier options wraps that of later ones.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// chainMiddleware wraps next so that the first of middleware is the outermost
+func chainMiddleware(middleware []Middleware, next http.RoundTripper) http.RoundTripper {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next
+}
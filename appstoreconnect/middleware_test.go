@@ -0,0 +1,70 @@
+package appstoreconnect_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := appstoreconnecttest.NewServer()
+	defer server.Close()
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+
+	calls := []string{}
+	var ops []*appstoreconnect.Operation
+	var statuses []int
+	record := func(name string) appstoreconnect.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return appstoreconnect.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name)
+				if op, ok := appstoreconnect.OperationFromContext(req.Context()); ok && name == "outer" {
+					ops = append(ops, op)
+				}
+				resp, err := next.RoundTrip(req)
+				if err == nil && name == "outer" {
+					statuses = append(statuses, resp.StatusCode)
+				}
+				return resp, err
+			})
+		}
+	}
+
+	client, err := server.Client(
+		appstoreconnect.WithMiddleware(record("outer"), record("inner")),
+		appstoreconnect.WithClock(func() time.Time { return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.SalesReport.Get(may1, appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.FinanceReport.Get(may1, "US"); err != appstoreconnect.ErrNoData {
+		t.Error("expected no data", err)
+	}
+
+	if !reflect.DeepEqual(calls, []string{"outer", "inner", "outer", "inner"}) {
+		t.Error("unexpected middleware order", calls)
+	}
+	if !reflect.DeepEqual(statuses, []int{http.StatusOK, http.StatusNotFound}) {
+		t.Error("unexpected statuses", statuses)
+	}
+	if len(ops) != 2 {
+		t.Fatal("expected an operation per request", ops)
+	}
+
+	sales, finance := ops[0], ops[1]
+	if sales.Name != "SalesReport.Get" || sales.Filters["filter[reportDate]"] != "2024-05-01" ||
+		sales.VendorNumber != appstoreconnecttest.VendorNumber || !sales.Period.Start.Equal(may1) || sales.Period.Frequency != appstoreconnect.Daily {
+		t.Errorf("unexpected sales operation %+v", sales)
+	}
+	if finance.Name != "FinanceReport.Get" || finance.Period.Frequency != appstoreconnect.FiscalMonthly ||
+		!finance.Period.Start.Equal(time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected finance operation %+v", finance)
+	}
+}
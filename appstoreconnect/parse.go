@@ -0,0 +1,128 @@
+package appstoreconnect
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// Parsing of report files, whether fetched from the api, the cache or downloaded by hand
+
+var (
+	// ErrUnknownReport the header does not match any report this package can parse
+	ErrUnknownReport = errors.New("parse: unrecognized report header")
+
+	// ErrUnsupportedReport the header belongs to a report this package cannot parse yet
+	ErrUnsupportedReport = errors.New("parse: unsupported report")
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// ParseReport detects the kind of report in r and decodes it into a *SalesReportResponse or
+// a *FinanceReportResponse. The input may be gzipped, in which case a corrupt or truncated
+// file is an error.
+func ParseReport(r io.Reader) (encoding.Encodable, error) {
+	rc, err := maybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	br := bufio.NewReader(stripBOM(rc))
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	header := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+	reportType, err := DetectReportType(header)
+	if err != nil {
+		return nil, err
+	}
+
+	full := io.MultiReader(strings.NewReader(line), br)
+	var report encoding.Encodable
+	if reportType == ReportFinancial {
+		report, err = ParseFinanceReport(full)
+	} else {
+		report, err = ParseSalesReport(full)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// gzip only verifies its checksum once read to the end
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// DetectReportType identifies the report a header row belongs to. Sales summary and finance
+// reports are supported, other sales and trends reports fail with ErrUnsupportedReport.
+func DetectReportType(header []string) (ReportType, error) {
+	has := func(cols ...string) bool {
+		for _, c := range cols {
+			if !slices.Contains(header, c) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case has("Vendor Identifier", "Partner Share", "Extended Partner Share"):
+		return ReportFinancial, nil
+	case has("SKU", "Units", "Product Type Identifier", "Developer Proceeds"):
+		return ReportSales, nil
+	}
+
+	for _, u := range unsupportedReports {
+		if has(u.columns...) {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedReport, u.name)
+		}
+	}
+	return "", ErrUnknownReport
+}
+
+// unsupportedReports recognises the other sales and trends reports by columns only they have
+var unsupportedReports = []struct {
+	name    string
+	columns []string
+}{
+	{"SUBSCRIBER DETAILED", []string{"Subscriber ID", "Event Date"}},
+	{"SUBSCRIPTION_EVENT SUMMARY", []string{"Event", "Event Date", "Subscription Name"}},
+	{"SUBSCRIPTION SUMMARY", []string{"Subscription Name", "Active Standard Price Subscriptions"}},
+	{"PRE_ORDER SUMMARY", []string{"Preorder Start", "Ordered", "Canceled"}},
+	{"SALES OPT_IN", []string{"Email Address", "Report Start Date"}},
+}
+
+// maybeGunzip transparently decompresses r if it starts with the gzip magic number
+func maybeGunzip(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return io.NopCloser(br), nil
+}
+
+// stripBOM drops the byte order mark spreadsheet tools like to add to exported files
+func stripBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	b, err := br.Peek(len(utf8BOM))
+	if err == nil && bytes.Equal(b, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br
+}
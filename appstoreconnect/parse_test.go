@@ -0,0 +1,50 @@
+package appstoreconnect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(salesTsv))
+	w.Close()
+
+	r, err := ParseReport(bytes.NewReader(gz.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales, ok := r.(*SalesReportResponse)
+	if !ok || len(sales.Reports) != 1 || sales.Reports[0].Units != 3 || sales.Reports[0].SKU != "com.example.app" {
+		t.Errorf("unexpected sales report %+v", r)
+	}
+
+	r, err = ParseReport(strings.NewReader("\ufeff" + financeTsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	finance, ok := r.(*FinanceReportResponse)
+	if !ok || len(finance.Reports) != 1 || finance.Reports[0].ExtendedPartnerShare != "2.10" {
+		t.Errorf("unexpected finance report %+v", r)
+	}
+
+	if _, err := ParseReport(strings.NewReader("a\tb\n1\t2\n")); err != ErrUnknownReport {
+		t.Error("expected unknown report error")
+	}
+
+	subscription := "App Name\tApp Apple ID\tSubscription Name\tSubscription Apple ID\tActive Standard Price Subscriptions\n"
+	if _, err := ParseReport(strings.NewReader(subscription)); !errors.Is(err, ErrUnsupportedReport) || !strings.HasSuffix(err.Error(), "SUBSCRIPTION SUMMARY") {
+		t.Error("expected unsupported report error", err)
+	}
+
+	// a corrupt checksum fails rather than returning what was read
+	b := gz.Bytes()
+	b[len(b)-8] ^= 0xff
+	if _, err := ParseReport(bytes.NewReader(b)); err != gzip.ErrChecksum {
+		t.Error("expected a checksum error", err)
+	}
+}
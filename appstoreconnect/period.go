@@ -0,0 +1,154 @@
+package appstoreconnect
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+// Period is a single report period
+type Period struct {
+	Frequency Frequency
+	// Date identifies the period in report requests, the sunday ending the week for weekly
+	// periods and the month a fiscal period is named after for fiscal ones
+	Date time.Time
+	// Start is the first day of the period
+	Start time.Time
+	// End is the last day of the period
+	End time.Time
+}
+
+// NewPeriod returns the period of frequency f containing date
+func NewPeriod(date time.Time, f Frequency) Period {
+	d := roundDown(civilDate(date), f)
+	p := Period{
+		Frequency: f,
+		Date:      d,
+		Start:     d,
+		End:       periodEnd(d, f),
+	}
+	switch f {
+	case Weekly:
+		p.Start = d.AddDate(0, 0, -6)
+	case FiscalMonthly:
+		fp := AppleFiscalCalendar.PeriodNamed(d)
+		p.Start, p.End = fp.Start, fp.End
+	}
+	return p
+}
+
+// Contains reports whether the day t falls on is in the period
+func (p Period) Contains(t time.Time) bool {
+	d := civilDate(t)
+	return !d.Before(p.Start) && !d.After(p.End)
+}
+
+// Days is the number of days in the period
+func (p Period) Days() int {
+	return int(p.End.Sub(p.Start).Hours()/24) + 1
+}
+
+// Periods iterates the periods of the range without changing it, so a range may be iterated
+// any number of times
+func (t *TimeRange) Periods() iter.Seq[Period] {
+	return func(yield func(Period) bool) {
+		for d := t.Start; !d.After(t.End); d = addFrequency(d, t.Frequency) {
+			if !yield(NewPeriod(d, t.Frequency)) {
+				return
+			}
+		}
+	}
+}
+
+// Len is the number of periods in the range, counted without iterating so that huge ranges
+// are cheap to measure
+func (t *TimeRange) Len() int {
+	start, end := roundDown(civilDate(t.Start), t.Frequency), roundDown(civilDate(t.End), t.Frequency)
+	if start.After(end) {
+		return 0
+	}
+	// days from unix seconds, as the span of a huge range overflows a Duration
+	days := int((end.Unix() - start.Unix()) / (24 * 60 * 60))
+	switch t.Frequency {
+	case Weekly:
+		return days/7 + 1
+	case Monthly, FiscalMonthly:
+		return (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month()) + 1
+	case Yearly:
+		return end.Year() - start.Year() + 1
+	}
+	return days + 1
+}
+
+// Contains reports whether the day t falls on is covered by the range
+func (t *TimeRange) Contains(day time.Time) bool {
+	d := civilDate(day)
+	first, last := NewPeriod(t.Start, t.Frequency), NewPeriod(t.End, t.Frequency)
+	return !t.Start.After(t.End) && !d.Before(first.Start) && !d.After(last.End)
+}
+
+// Split returns a range over the same days at frequency f. Splitting into a coarser frequency
+// widens the range to whole periods.
+func (t *TimeRange) Split(f Frequency) *TimeRange {
+	return NewTimeRange(NewPeriod(t.Start, t.Frequency).Start, NewPeriod(t.End, t.Frequency).End, f)
+}
+
+// Decompose covers the days of the range with the fewest periods of the given frequencies,
+// all of Yearly, Monthly, Weekly and Daily if none are given
+func (t *TimeRange) Decompose(frequencies ...Frequency) []Period {
+	if t.Start.After(t.End) {
+		return nil
+	}
+	return Decompose(NewPeriod(t.Start, t.Frequency).Start, NewPeriod(t.End, t.Frequency).End, frequencies...)
+}
+
+// Decompose covers the days start through end with the fewest periods of the given frequencies,
+// all of Yearly, Monthly, Weekly and Daily if none are given. Daily periods are always allowed
+// so that any span can be covered. Ties go to the frequency listed first.
+func Decompose(start time.Time, end time.Time, frequencies ...Frequency) []Period {
+	return decompose(start, end, frequencies, nil)
+}
+
+// decompose is Decompose only considering periods which usable returns true for, besides days
+func decompose(start time.Time, end time.Time, frequencies []Frequency, usable func(Period) bool) []Period {
+	if len(frequencies) == 0 {
+		frequencies = []Frequency{Yearly, Monthly, Weekly, Daily}
+	}
+	if !slices.Contains(frequencies, Daily) {
+		frequencies = append(slices.Clone(frequencies), Daily)
+	}
+
+	start, end = civilDate(start), civilDate(end)
+	if start.After(end) {
+		return nil
+	}
+	n := int(end.Sub(start).Hours()/24) + 1
+
+	// cost[i] is the fewest periods covering day i through the end, choice[i] the period used
+	cost := make([]int, n+1)
+	choice := make([]Period, n)
+	for i := n - 1; i >= 0; i-- {
+		day := start.AddDate(0, 0, i)
+		cost[i] = -1
+		for _, f := range frequencies {
+			p := NewPeriod(day, f)
+			if !p.Start.Equal(day) || p.End.After(end) {
+				continue
+			}
+			if f != Daily && usable != nil && !usable(p) {
+				continue
+			}
+			c := cost[i+p.Days()] + 1
+			if cost[i] < 0 || c < cost[i] {
+				cost[i] = c
+				choice[i] = p
+			}
+		}
+	}
+
+	var periods []Period
+	for i := 0; i < n; i += choice[i].Days() {
+		periods = append(periods, choice[i])
+	}
+	return periods
+}
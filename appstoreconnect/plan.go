@@ -0,0 +1,76 @@
+package appstoreconnect
+
+import (
+	"time"
+)
+
+// Apple offers the same sales data at several frequencies. A year of daily reports is 365
+// requests where the yearly report is one, so ranges are planned with the coarsest reports
+// which exactly cover them.
+
+// reportFrequencies are the frequencies Apple offers for each report type, coarsest first
+var reportFrequencies = map[ReportType][]Frequency{
+	ReportSales:                           {Yearly, Monthly, Weekly, Daily},
+	ReportPreOrder:                        {Yearly, Monthly, Weekly, Daily},
+	ReportNewsstand:                       {Weekly, Daily},
+	ReportSubscription:                    {Daily},
+	ReportSubscriptionEvent:               {Daily},
+	ReportSubscriber:                      {Daily},
+	ReportSubscriptionOfferCodeRedemption: {Weekly, Daily},
+}
+
+// PlanOptions tunes how Plan covers a range
+type PlanOptions struct {
+	// KeepDaily plans one request per day, for when rows must not span more than a day
+	KeepDaily bool
+
+	// Frequencies limits the frequencies used, further than what the report type offers
+	Frequencies []Frequency
+
+	// Now decides which periods have been published, time.Now if nil. Unpublished years,
+	// months and weeks are covered by days instead.
+	Now func() time.Time
+}
+
+// ReportFrequencies returns the frequencies Apple offers reportType at, coarsest first
+func ReportFrequencies(reportType ReportType) []Frequency {
+	if f, ok := reportFrequencies[reportType]; ok {
+		return f
+	}
+	return []Frequency{Daily}
+}
+
+// Plan covers the days of timeRange with the fewest reports of reportType: complete years, then
+// months, weeks and days
+func Plan(timeRange *TimeRange, reportType ReportType, opts PlanOptions) []Period {
+	frequencies := ReportFrequencies(reportType)
+	if opts.KeepDaily {
+		frequencies = []Frequency{Daily}
+	}
+	if len(opts.Frequencies) > 0 {
+		var allowed []Frequency
+		for _, f := range frequencies {
+			for _, o := range opts.Frequencies {
+				if f == o {
+					allowed = append(allowed, f)
+				}
+			}
+		}
+		frequencies = allowed
+	}
+
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	at := now()
+
+	if timeRange.Start.After(timeRange.End) {
+		return nil
+	}
+	start := NewPeriod(timeRange.Start, timeRange.Frequency).Start
+	end := NewPeriod(timeRange.End, timeRange.Frequency).End
+	return decompose(start, end, frequencies, func(p Period) bool {
+		return IsPublished(reportType, p.Date, p.Frequency, at)
+	})
+}
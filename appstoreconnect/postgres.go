@@ -0,0 +1,320 @@
+package appstoreconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// Loading reports into Postgres. Each report struct has a table with a column per field, created
+// and extended as fields are added. Every load replaces one period of one report stream in a
+// transaction and records it in a manifest, so restated periods can be loaded again safely.
+
+// Tables the Loader writes to
+const (
+	SalesTable    = "asc_sales_reports"
+	FinanceTable  = "asc_finance_reports"
+	ManifestTable = "asc_loads"
+)
+
+var (
+	// ErrUnknownReportType the report cannot be loaded
+	ErrUnknownReportType = errors.New("load: cannot load reports of this type")
+
+	// ErrVendorMismatch the report has rows of a vendor other than the key's
+	ErrVendorMismatch = errors.New("load: rows belong to another vendor than the key")
+)
+
+// keyColumns identify the report stream and period of each row, before the report's own columns
+var keyColumns = []string{"report_type", "report_sub_type", "frequency", "region_code", "period_date"}
+
+// reportTableTypes are the structs of the rows of each report table
+var reportTableTypes = map[string]reflect.Type{
+	SalesTable:   reflect.TypeFor[SalesReportItem](),
+	FinanceTable: reflect.TypeFor[FinanceReportItem](),
+}
+
+// insertBatch is the number of rows inserted per statement without COPY, well below the 65535
+// parameters a postgres statement may have
+const insertBatch = 500
+
+// Loader loads reports into Postgres
+type Loader struct {
+	db *sql.DB
+
+	// Copy bulk loads rows with COPY ... FROM STDIN, which only works with the lib/pq driver.
+	// Without it rows are inserted in batches, which works with any driver including pgx.
+	Copy bool
+}
+
+// NewLoader creates a loader bulk loading rows with COPY when db uses lib/pq and inserting
+// them in batches otherwise
+func NewLoader(db *sql.DB) *Loader {
+	return &Loader{db: db, Copy: isLibPQ(db)}
+}
+
+// isLibPQ reports whether db uses the lib/pq driver, without making every user of this
+// package depend on it
+func isLibPQ(db *sql.DB) bool {
+	t := reflect.TypeOf(db.Driver())
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.PkgPath() == "github.com/lib/pq"
+}
+
+// Migrate creates the report and manifest tables and adds columns for fields added to the
+// report structs since. It is safe to call every time the loader starts.
+func (l *Loader) Migrate() error {
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS " + quoteIdent(ManifestTable) + ` (
+	vendor_number text NOT NULL,
+	report_type text NOT NULL,
+	report_sub_type text NOT NULL,
+	frequency text NOT NULL,
+	region_code text NOT NULL,
+	period_date date NOT NULL,
+	row_count integer NOT NULL,
+	loaded_at timestamptz NOT NULL,
+	PRIMARY KEY (vendor_number, report_type, report_sub_type, frequency, region_code, period_date)
+)`,
+	}
+	for _, table := range []string{SalesTable, FinanceTable} {
+		stmts = append(stmts, "CREATE TABLE IF NOT EXISTS "+quoteIdent(table)+` (
+	report_type text NOT NULL,
+	report_sub_type text NOT NULL,
+	frequency text NOT NULL,
+	region_code text NOT NULL,
+	period_date date NOT NULL,
+	vendor_number text NOT NULL
+)`)
+		stmts = append(stmts, "CREATE INDEX IF NOT EXISTS "+quoteIdent(table+"_period")+" ON "+quoteIdent(table)+
+			" (vendor_number, report_type, frequency, period_date)")
+		for f := range reportTableTypes[table].Fields() {
+			stmts = append(stmts, "ALTER TABLE "+quoteIdent(table)+" ADD COLUMN IF NOT EXISTS "+
+				quoteIdent(columnName(f.Name))+" "+columnType(f.Type))
+		}
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Load replaces the period of key containing date with report, a *SalesReportResponse or
+// *FinanceReportResponse. It is a SyncFunc, so a Syncer can load straight into Postgres. Rows
+// must be the key's vendor's, load each vendor of a GetVendors response with its own key.
+func (l *Loader) Load(key SyncKey, date time.Time, report encoding.Encodable) error {
+	// rows from the api are tagged with their vendor, parsed files may not be
+	var table string
+	var rows []reflect.Value
+	var vendors []string
+	switch r := report.(type) {
+	case *SalesReportResponse:
+		table = SalesTable
+		for _, item := range r.Reports {
+			item = item.Clone()
+			if item.VendorNumber == "" {
+				item.VendorNumber = key.VendorNumber
+			}
+			vendors = append(vendors, item.VendorNumber)
+			rows = append(rows, reflect.ValueOf(item).Elem())
+		}
+	case *FinanceReportResponse:
+		table = FinanceTable
+		for _, item := range r.Reports {
+			item = item.Clone()
+			if item.VendorNumber == "" {
+				item.VendorNumber = key.VendorNumber
+			}
+			vendors = append(vendors, item.VendorNumber)
+			rows = append(rows, reflect.ValueOf(item).Elem())
+		}
+	default:
+		return ErrUnknownReportType
+	}
+	// the period is replaced for the key's vendor only, other vendors' rows would pile up
+	for _, vendor := range vendors {
+		if vendor != key.VendorNumber {
+			return fmt.Errorf("%w: %s", ErrVendorMismatch, vendor)
+		}
+	}
+
+	f := key.Frequency
+	if key.ReportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+	period := NewPeriod(date, f).Date
+	keyArgs := []any{string(key.ReportType), string(key.ReportSubType), string(f), key.RegionCode, period}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	del := "DELETE FROM " + quoteIdent(table) +
+		" WHERE report_type = $1 AND report_sub_type = $2 AND frequency = $3 AND region_code = $4 AND period_date = $5 AND vendor_number = $6"
+	if _, err := tx.Exec(del, append(keyArgs, key.VendorNumber)...); err != nil {
+		return err
+	}
+
+	columns := slices.Clone(keyColumns)
+	for f := range reportTableTypes[table].Fields() {
+		columns = append(columns, columnName(f.Name))
+	}
+	values := func(row reflect.Value) []any {
+		args := slices.Clone(keyArgs)
+		for _, v := range row.Fields() {
+			args = append(args, v.Interface())
+		}
+		return args
+	}
+
+	if l.Copy {
+		err = copyRows(tx, table, columns, rows, values)
+	} else {
+		err = insertRows(tx, table, columns, rows, values)
+	}
+	if err != nil {
+		return err
+	}
+
+	manifest := "INSERT INTO " + quoteIdent(ManifestTable) +
+		" (report_type, report_sub_type, frequency, region_code, period_date, vendor_number, row_count, loaded_at)" +
+		" VALUES ($1, $2, $3, $4, $5, $6, $7, $8)" +
+		" ON CONFLICT (vendor_number, report_type, report_sub_type, frequency, region_code, period_date)" +
+		" DO UPDATE SET row_count = EXCLUDED.row_count, loaded_at = EXCLUDED.loaded_at"
+	if _, err := tx.Exec(manifest, append(keyArgs, key.VendorNumber, len(rows), time.Now().UTC())...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Loaded lists the periods of key in the manifest, in order
+func (l *Loader) Loaded(key SyncKey) ([]time.Time, error) {
+	f := key.Frequency
+	if key.ReportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+	rows, err := l.db.Query("SELECT period_date FROM "+quoteIdent(ManifestTable)+
+		" WHERE vendor_number = $1 AND report_type = $2 AND report_sub_type = $3 AND frequency = $4 AND region_code = $5"+
+		" ORDER BY period_date",
+		key.VendorNumber, string(key.ReportType), string(key.ReportSubType), string(f), key.RegionCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []time.Time{}
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		periods = append(periods, civilDate(t))
+	}
+	return periods, rows.Err()
+}
+
+// copyRows bulk loads rows the way lib/pq does, with a prepared COPY statement executed once per
+// row and once more without arguments to finish
+func copyRows(tx *sql.Tx, table string, columns []string, rows []reflect.Value, values func(reflect.Value) []any) error {
+	stmt, err := tx.Prepare(copyStatement(table, columns))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.Exec(values(row)...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.Exec()
+	return err
+}
+
+// copyStatement is the statement lib/pq's CopyIn builds
+func copyStatement(table string, columns []string) string {
+	return "COPY " + quoteIdent(table) + " (" + quoteIdents(columns) + ") FROM STDIN"
+}
+
+func insertRows(tx *sql.Tx, table string, columns []string, rows []reflect.Value, values func(reflect.Value) []any) error {
+	for start := 0; start < len(rows); start += insertBatch {
+		batch := rows[start:min(start+insertBatch, len(rows))]
+
+		var b strings.Builder
+		b.WriteString("INSERT INTO " + quoteIdent(table) + " (" + quoteIdents(columns) + ") VALUES ")
+		args := []any{}
+		for i, row := range batch {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("(")
+			for j := range columns {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString("$" + strconv.Itoa(len(args)+j+1))
+			}
+			b.WriteString(")")
+			args = append(args, values(row)...)
+		}
+		if _, err := tx.Exec(b.String(), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnName is the snake case of a field name, keeping acronyms together: ProviderCountry is
+// provider_country and ISANOtherIdentifier isan_other_identifier
+func columnName(field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func columnType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "integer"
+	}
+	return "text"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteIdents(idents []string) string {
+	quoted := make([]string, len(idents))
+	for i, s := range idents {
+		quoted[i] = quoteIdent(s)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,89 @@
+package appstoreconnect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Reports exactly as apple served them, for auditing and archival alongside the parsed rows
+
+// RawReport is a report file as apple served it
+type RawReport struct {
+	// Operation is the call the report was requested for
+	Operation *Operation `json:"-"`
+	// URL is the request url, including the filters
+	URL string `json:"url"`
+	// Header is the response header, nil when the report came from the cache
+	Header http.Header `json:"header,omitempty"`
+	// Body is the gzipped report
+	Body []byte `json:"-"`
+	// SHA256 is the hex encoded digest of Body
+	SHA256 string `json:"sha256"`
+	// Size is the length of Body
+	Size int `json:"size"`
+	// FetchedAt is when the report was received or read from the cache
+	FetchedAt time.Time `json:"fetched_at"`
+	// Cached is whether the report came from the cache rather than apple
+	Cached bool `json:"cached"`
+}
+
+func newRawReport(op *Operation, url string, header http.Header, body []byte, cached bool) *RawReport {
+	sum := sha256.Sum256(body)
+	return &RawReport{
+		Operation: op,
+		URL:       url,
+		Header:    header,
+		Body:      body,
+		SHA256:    hex.EncodeToString(sum[:]),
+		Size:      len(body),
+		FetchedAt: time.Now().UTC(),
+		Cached:    cached,
+	}
+}
+
+// WithRawHandler calls fn with every report the client receives, before it is parsed. An error
+// from fn fails the request, so a report is never returned without having been archived.
+func WithRawHandler(fn func(*RawReport) error) ClientOption {
+	return func(c *Client) {
+		c.onRaw = fn
+	}
+}
+
+// FileName is the name the App Store Connect website gives the download of the report
+func (r *RawReport) FileName() string {
+	op := r.Operation
+	if op.Path == pathFinanceReports {
+		return FinanceReportFileName(op.VendorNumber, op.Period, op.Filters["filter[regionCode]"])
+	}
+	reportType := ReportType(op.Filters["filter[reportType]"])
+	reportSubType := ReportSubType(op.Filters["filter[reportSubType]"])
+	return SalesReportFileName(op.VendorNumber, reportType, reportSubType, op.Period)
+}
+
+// SalesReportFileName is the name the App Store Connect website gives a sales report download,
+// like S_D_80000000_20240501.txt.gz for a summary sales report. Other reports are prefixed with
+// their type and sub type instead of S.
+func SalesReportFileName(vendorNumber string, reportType ReportType, reportSubType ReportSubType, p Period) string {
+	prefix := "S"
+	if reportType != ReportSales || reportSubType != SubReportSummary {
+		prefix = string(reportType) + "_" + string(reportSubType)
+	}
+	var date string
+	switch p.Frequency {
+	case Yearly:
+		date = p.Date.Format("2006")
+	case Monthly:
+		date = p.Date.Format("200601")
+	default:
+		date = p.Date.Format("20060102")
+	}
+	return prefix + "_" + string(p.Frequency)[:1] + "_" + vendorNumber + "_" + date + ".txt.gz"
+}
+
+// FinanceReportFileName is the name the App Store Connect website gives a finance report
+// download, like 80000000_0524_US.txt.gz
+func FinanceReportFileName(vendorNumber string, p Period, regionCode string) string {
+	return vendorNumber + "_" + p.Date.Format("0106") + "_" + regionCode + ".txt.gz"
+}
@@ -0,0 +1,69 @@
+package appstoreconnect_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+func TestGetRaw(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+
+	r, raw, err := client.SalesReport.GetRaw(may1, appstoreconnect.Daily, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Reports) != 1 {
+		t.Error("unexpected rows", r.Reports)
+	}
+
+	sum := sha256.Sum256(raw.Body)
+	if raw.SHA256 != hex.EncodeToString(sum[:]) || raw.Size != len(raw.Body) || raw.Body[0] != 0x1f {
+		t.Error("expected the gzipped body and its digest", raw.SHA256)
+	}
+	if !strings.HasPrefix(raw.URL, server.URL+"/v1/salesReports?") || !strings.Contains(raw.URL, "2024-05-01") {
+		t.Error("unexpected url", raw.URL)
+	}
+	if raw.Header.Get("Content-Type") != "application/a-gzip" || raw.Cached {
+		t.Error("unexpected header", raw.Header)
+	}
+	if name := raw.FileName(); name != "S_D_80000000_20240501.txt.gz" {
+		t.Error("unexpected file name", name)
+	}
+}
+
+func TestRawHandler(t *testing.T) {
+	server, _ := newServerClient(t)
+	server.AddFinanceReport("2024-05", "US", financeTsv)
+
+	var raws []*appstoreconnect.RawReport
+	archiveErr := errors.New("archive unavailable")
+	var fail bool
+	client, err := server.Client(appstoreconnect.WithRawHandler(func(raw *appstoreconnect.RawReport) error {
+		if fail {
+			return archiveErr
+		}
+		raws = append(raws, raw)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.FinanceReport.Get(may1, "US"); err != nil {
+		t.Fatal(err)
+	}
+	if len(raws) != 1 || raws[0].FileName() != "80000000_0524_US.txt.gz" {
+		t.Error("expected the raw report to be handled", raws)
+	}
+
+	fail = true
+	if _, err := client.FinanceReport.Get(may1, "US"); err != archiveErr {
+		t.Error("expected the handler error", err)
+	}
+}
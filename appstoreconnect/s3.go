@@ -0,0 +1,208 @@
+package appstoreconnect
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An S3 compatible Sink signing requests with AWS Signature Version 4, enough to put and check
+// for objects in AWS, MinIO, R2 and the like without an SDK
+
+var (
+	// ErrS3URL the url is not like s3://bucket/prefix
+	ErrS3URL = errors.New("s3: url must be like s3://bucket/prefix")
+
+	// ErrS3Credentials no access key and secret were given
+	ErrS3Credentials = errors.New("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+)
+
+// S3Sink exports to a bucket of an S3 compatible object store
+type S3Sink struct {
+	// Endpoint is the url of the service, objects are addressed path style below it
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	now func() time.Time
+}
+
+// NewS3SinkFromEnv creates a sink for s3://bucket/prefix with the standard AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION variables. AWS_ENDPOINT_URL_S3 or
+// AWS_ENDPOINT_URL point it at another S3 compatible service.
+func NewS3SinkFromEnv(u string) (*S3Sink, error) {
+	bucket, prefix, err := parseS3URL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &S3Sink{
+		Region:          os.Getenv("AWS_REGION"),
+		Bucket:          bucket,
+		Prefix:          prefix,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, ErrS3Credentials
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	s.Endpoint = os.Getenv("AWS_ENDPOINT_URL_S3")
+	if s.Endpoint == "" {
+		s.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	return s, nil
+}
+
+func parseS3URL(u string) (string, string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme != "s3" || parsed.Host == "" {
+		return "", "", ErrS3URL
+	}
+	return parsed.Host, strings.Trim(parsed.Path, "/"), nil
+}
+
+// Exists reports whether an object is at path below the prefix
+func (s *S3Sink) Exists(name string) (bool, error) {
+	resp, err := s.do(http.MethodHead, name, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode > 299:
+		return false, errors.New("s3: HEAD " + name + ": " + resp.Status)
+	}
+	return true, nil
+}
+
+// Write puts body at path below the prefix
+func (s *S3Sink) Write(name string, body []byte) error {
+	resp, err := s.do(http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(resp.Body)
+		return errors.New("s3: PUT " + name + ": " + resp.Status + " " + string(msg))
+	}
+	return nil
+}
+
+func (s *S3Sink) do(method string, name string, body []byte) (*http.Response, error) {
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + path.Join(s.Prefix, name)
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(name, ".json") {
+		req.Header.Set("Content-Type", "application/json")
+	} else if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	signV4(req, body, s.AccessKeyID, s.SecretAccessKey, s.Region, "s3", now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// signV4 signs req with AWS Signature Version 4, covering the host and every header already set
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_sigv-create-signed-request.html
+func signV4(req *http.Request, body []byte, accessKey string, secret string, region string, service string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	names := slices.Sorted(maps.Keys(headers))
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(q url.Values) string {
+	parts := []string{}
+	for k, vs := range q {
+		for _, v := range vs {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	slices.Sort(parts)
+	return strings.ReplaceAll(strings.Join(parts, "&"), "+", "%20")
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
@@ -7,7 +7,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"reflect"
+	"slices"
 	"time"
 
 	"github.com/zackb/go-appstoreconnect/encoding"
@@ -25,8 +28,15 @@ type ReportSubType string
 // SalesReportResponse represents the response from a sales report request
 type SalesReportResponse struct {
 	Reports []*SalesReportItem
+
+	// vendors is set on responses merging several vendors, whose csv and tsv end with a
+	// Vendor Number column
+	vendors bool
 }
 
+// vendorNumberHeader is the column added to the csv and tsv of several vendors' reports
+const vendorNumberHeader = "Vendor Number"
+
 // SalesReportItem is information returned about SALES -> SUMMARY reports
 type SalesReportItem struct {
 	Provider              string `tsv:"Provider"`
@@ -57,6 +67,8 @@ type SalesReportItem struct {
 	PreservedPricing      string `tsv:"Preserved Pricing"`
 	Client                string `tsv:"Client"`
 	OrderType             string `tsv:"Order Type"`
+	// VendorNumber is the vendor the row was fetched for, not part of apple's report
+	VendorNumber string `tsv:"-"`
 }
 
 // SalesReport service is responsible for communicating with the "salesRepors" endpoint
@@ -75,11 +87,20 @@ const (
 )
 
 const (
-	ReportSales ReportType = "SALES"
+	ReportSales                           ReportType = "SALES"
+	ReportPreOrder                        ReportType = "PRE_ORDER"
+	ReportNewsstand                       ReportType = "NEWSSTAND"
+	ReportSubscription                    ReportType = "SUBSCRIPTION"
+	ReportSubscriptionEvent               ReportType = "SUBSCRIPTION_EVENT"
+	ReportSubscriber                      ReportType = "SUBSCRIBER"
+	ReportSubscriptionOfferCodeRedemption ReportType = "SUBSCRIPTION_OFFER_CODE_REDEMPTION"
+	ReportFinancial                       ReportType = "FINANCIAL"
 )
 
 const (
-	SubReportSummary ReportSubType = "SUMMARY"
+	SubReportSummary  ReportSubType = "SUMMARY"
+	SubReportDetailed ReportSubType = "DETAILED"
+	SubReportOptIn    ReportSubType = "OPT_IN"
 )
 
 func (f *Frequency) String() string {
@@ -125,14 +146,31 @@ func (s *SalesReportItem) Clone() *SalesReportItem {
 	c.PreservedPricing = s.PreservedPricing
 	c.Client = s.Client
 	c.OrderType = s.OrderType
+	c.VendorNumber = s.VendorNumber
 	return &c
 }
 
-// GetRange TODO
+// GetRange gets sales report data for every period in the given TimeRange. Periods without data
+// are skipped. Reaching a period Apple has not published yet stops the range, returning what
+// was fetched so far along with ErrNotYetPublished.
 func (c *SalesReport) GetRange(timeRange *TimeRange, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
+	return c.getPeriods(timeRange.Periods(), reportType, reportSubType)
+}
+
+// GetPlanned gets sales report data for the days of the given TimeRange with as few requests as
+// possible, see Plan. Rows of the merged response cover different lengths of time when the plan
+// mixes frequencies, set opts.KeepDaily when every row must be a single day.
+func (c *SalesReport) GetPlanned(timeRange *TimeRange, reportType ReportType, reportSubType ReportSubType, opts PlanOptions) (*SalesReportResponse, error) {
+	if opts.Now == nil {
+		opts.Now = c.client.clock
+	}
+	return c.getPeriods(slices.Values(Plan(timeRange, reportType, opts)), reportType, reportSubType)
+}
+
+func (c *SalesReport) getPeriods(periods iter.Seq[Period], reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
 	sr := SalesReportResponse{}
-	for timeRange.Next() {
-		s1, err := c.Get(timeRange.Current(), timeRange.Frequency, reportType, reportSubType)
+	for p := range periods {
+		s1, err := c.Get(p.Date, p.Frequency, reportType, reportSubType)
 		if err != nil {
 			if err == ErrNoData {
 				continue
@@ -144,6 +182,21 @@ func (c *SalesReport) GetRange(timeRange *TimeRange, reportType ReportType, repo
 	return &sr, nil
 }
 
+// GetVendors gets sales report data for the TimeRange from each vendor concurrently. Rows are
+// tagged with the vendor they came from, which the csv and tsv add as a Vendor Number column,
+// and merged in the order of vendors.
+func (c *SalesReport) GetVendors(vendors []string, timeRange *TimeRange, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
+	responses, err := fanOut(vendors, func(vendor string) (*SalesReportResponse, error) {
+		return c.client.WithVendor(vendor).SalesReport.GetRange(timeRange, reportType, reportSubType)
+	})
+
+	sr := SalesReportResponse{vendors: true}
+	for _, r := range responses {
+		sr.Reports = append(sr.Reports, r.Reports...)
+	}
+	return &sr, err
+}
+
 // GetDay gets one day of sales report data
 func (s *SalesReport) GetDay(day string, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
 	t, err := NewTime(day)
@@ -171,20 +224,55 @@ func (s *SalesReport) GetYear(year string, reportType ReportType, reportSubType
 	return s.Get(t, Yearly, reportType, reportSubType)
 }
 
+// Get gets the sales report for the period of the given frequency containing date. A 404 is
+// ErrNotYetPublished before Apple's publishing time for the period and ErrNoData after.
 func (c *SalesReport) Get(date time.Time, frequency Frequency, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, error) {
+	ret, _, err := c.GetRaw(date, frequency, reportType, reportSubType)
+	return ret, err
+}
+
+// GetRaw is Get also returning the report file exactly as apple served it
+func (c *SalesReport) GetRaw(date time.Time, frequency Frequency, reportType ReportType, reportSubType ReportSubType) (*SalesReportResponse, *RawReport, error) {
 	params := map[string]string{
 		"filter[frequency]":     frequency.String(),
 		"filter[reportDate]":    timeToReportDate(date, frequency),
 		"filter[reportType]":    reportType.String(),
 		"filter[reportSubType]": reportSubType.String(),
 	}
-	b, err := c.client.get(pathSalesReports, params)
+	raw, err := c.client.getRaw(&Operation{
+		Name:    "SalesReport.Get",
+		Path:    pathSalesReports,
+		Filters: params,
+		Period:  NewPeriod(date, frequency),
+	})
+	if err == ErrNoData && !IsPublished(reportType, date, frequency, c.client.clock()) {
+		return nil, nil, ErrNotYetPublished
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ret, err := ParseSalesReport(bytes.NewReader(raw.Body))
+	if err != nil {
+		return nil, raw, err
+	}
+	for _, r := range ret.Reports {
+		r.VendorNumber = c.client.vendorNumber
+	}
+	return ret, raw, nil
+}
+
+// ParseSalesReport decodes a sales report, such as one downloaded from the App Store Connect
+// website. The input may be gzipped.
+func ParseSalesReport(r io.Reader) (*SalesReportResponse, error) {
+	rc, err := maybeGunzip(r)
 	if err != nil {
 		return nil, err
 	}
+	defer rc.Close()
 
 	data := SalesReportItem{}
-	p, err := encoding.NewTsvParser(bytes.NewReader(b), &data)
+	p, err := encoding.NewTsvParser(stripBOM(rc), &data)
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +295,9 @@ func (s *SalesReportItem) GetHeader() []string {
 	t := reflect.TypeFor[SalesReportItem]()
 	tags := []string{}
 	for field := range t.Fields() {
-		tag := field.Tag.Get("tsv")
-		tags = append(tags, tag)
+		if tag := field.Tag.Get("tsv"); tag != "-" {
+			tags = append(tags, tag)
+		}
 	}
 
 	return tags
@@ -217,8 +306,10 @@ func (s *SalesReportItem) GetHeader() []string {
 func (s *SalesReportItem) Values() []string {
 	vals := []string{}
 	t := reflect.ValueOf(s).Elem()
-	for _, f := range t.Fields() {
-		vals = append(vals, fmt.Sprintf("%v", f.Interface()))
+	for field, f := range t.Fields() {
+		if field.Tag.Get("tsv") != "-" {
+			vals = append(vals, fmt.Sprintf("%v", f.Interface()))
+		}
 	}
 
 	return vals
@@ -235,12 +326,18 @@ func (s *SalesReportResponse) ToCsv() ([]byte, error) {
 	w := csv.NewWriter(buf)
 	d := SalesReportItem{}
 	headers := d.GetHeader()
+	if s.vendors {
+		headers = append(headers, vendorNumberHeader)
+	}
 	if err := w.Write(headers); err != nil {
 		return nil, err
 	}
 
 	for _, r := range s.Reports {
 		values := r.Values()
+		if s.vendors {
+			values = append(values, r.VendorNumber)
+		}
 		if err := w.Write(values); err != nil {
 			return nil, err
 		}
@@ -257,12 +354,18 @@ func (s *SalesReportResponse) ToTsv() ([]byte, error) {
 	w.Comma = '\t'
 	d := SalesReportItem{}
 	headers := d.GetHeader()
+	if s.vendors {
+		headers = append(headers, vendorNumberHeader)
+	}
 	if err := w.Write(headers); err != nil {
 		return nil, err
 	}
 
 	for _, r := range s.Reports {
 		values := r.Values()
+		if s.vendors {
+			values = append(values, r.VendorNumber)
+		}
 		if err := w.Write(values); err != nil {
 			return nil, err
 		}
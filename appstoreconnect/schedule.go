@@ -0,0 +1,96 @@
+package appstoreconnect
+
+import (
+	"time"
+)
+
+// Apple publishes reports on a fixed schedule relative to Pacific Time. Asking for a report
+// before then gets the same 404 as a period with no sales, the schedule tells the two apart.
+// https://developer.apple.com/help/app-store-connect/reference/reporting/sales-and-trends-reports-availability
+
+// publishLag is how long after the end of a period (midnight Pacific) its report becomes available
+func publishLag(reportType ReportType, f Frequency) (days int, hours int) {
+	if reportType == ReportFinancial {
+		// financial reports are published within 45 days of the end of the fiscal period
+		return 45, 0
+	}
+	switch f {
+	case Monthly:
+		return 5, 8
+	case Yearly:
+		return 6, 8
+	default:
+		// daily reports arrive the next morning, weekly reports the monday after the week ends
+		return 0, 8
+	}
+}
+
+// LatestAvailable returns the most recent period of the given report type and frequency which
+// Apple should have published by now. Days are counted in ReportingLocation whatever the location of now.
+func LatestAvailable(reportType ReportType, f Frequency, now time.Time) time.Time {
+	if reportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+	p := periodOf(ReportingDay(now), f)
+	for !IsPublished(reportType, p, f, now) {
+		p = prevPeriod(p, f)
+	}
+	return p
+}
+
+// IsPublished reports whether the report for the period containing date should be available at now
+func IsPublished(reportType ReportType, date time.Time, f Frequency, now time.Time) bool {
+	return !now.Before(PublishedAt(reportType, date, f))
+}
+
+// PublishedAt returns when Apple publishes the report for the period containing date. As with
+// FinanceReport.Get, the month of date names the fiscal period of financial reports.
+func PublishedAt(reportType ReportType, date time.Time, f Frequency) time.Time {
+	if reportType == ReportFinancial {
+		f = FiscalMonthly
+	}
+	end := periodEnd(roundDown(civilDate(date), f), f)
+	days, hours := publishLag(reportType, f)
+	return time.Date(end.Year(), end.Month(), end.Day()+1+days, hours, 0, 0, 0, ReportingLocation)
+}
+
+// periodOf returns the value identifying the period which contains t. Weekly periods are
+// identified by the sunday which ends them, as Apple does.
+func periodOf(t time.Time, f Frequency) time.Time {
+	switch f {
+	case Weekly:
+		return weekEnding(t)
+	case Monthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case FiscalMonthly:
+		return AppleFiscalCalendar.PeriodOf(t).Month()
+	case Yearly:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+// periodEnd returns the last day of the period identified by p
+func periodEnd(p time.Time, f Frequency) time.Time {
+	switch f {
+	case Monthly:
+		return p.AddDate(0, 1, -1)
+	case FiscalMonthly:
+		return AppleFiscalCalendar.PeriodNamed(p).End
+	case Yearly:
+		return p.AddDate(1, 0, -1)
+	}
+	return p
+}
+
+func prevPeriod(p time.Time, f Frequency) time.Time {
+	switch f {
+	case Weekly:
+		return p.AddDate(0, 0, -7)
+	case Monthly, FiscalMonthly:
+		return p.AddDate(0, -1, 0)
+	case Yearly:
+		return p.AddDate(-1, 0, 0)
+	}
+	return p.AddDate(0, 0, -1)
+}
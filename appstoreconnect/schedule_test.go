@@ -0,0 +1,55 @@
+package appstoreconnect
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatestAvailable(t *testing.T) {
+	// 2019-09-10 07:00 PDT, the report for the 9th is not out until 08:00
+	now := time.Date(2019, 9, 10, 14, 0, 0, 0, time.UTC)
+	if d := LatestAvailable(ReportSales, Daily, now); !d.Equal(parseTime("2019-09-08")) {
+		t.Error("unexpected latest daily: " + d.String())
+	}
+
+	now = now.Add(2 * time.Hour)
+	if d := LatestAvailable(ReportSales, Daily, now); !d.Equal(parseTime("2019-09-09")) {
+		t.Error("unexpected latest daily: " + d.String())
+	}
+
+	if d := LatestAvailable(ReportSales, Weekly, now); !d.Equal(parseTime("2019-09-08")) {
+		t.Error("unexpected latest weekly: " + d.String())
+	}
+
+	if d := LatestAvailable(ReportSales, Monthly, now); !d.Equal(parseTime("2019-08")) {
+		t.Error("unexpected latest monthly: " + d.String())
+	}
+
+	if d := LatestAvailable(ReportSales, Yearly, now); !d.Equal(parseTime("2018")) {
+		t.Error("unexpected latest yearly: " + d.String())
+	}
+}
+
+func TestPublishedAt(t *testing.T) {
+	// daily reports at 08:00 Pacific the next day, PDT in september
+	at := PublishedAt(ReportSales, parseTime("2019-09-09"), Daily)
+	if !at.Equal(time.Date(2019, 9, 10, 15, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected daily publish time: " + at.String())
+	}
+
+	// PST in december, and any day of the week gives the week's report
+	at = PublishedAt(ReportSales, parseTime("2019-12-04"), Weekly)
+	if !at.Equal(time.Date(2019, 12, 9, 16, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected weekly publish time: " + at.String())
+	}
+
+	// fiscal august 2019 ends august 31st
+	at = PublishedAt(ReportFinancial, parseTime("2019-08"), Monthly)
+	if !at.Equal(time.Date(2019, 10, 16, 7, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected financial publish time: " + at.String())
+	}
+
+	if IsPublished(ReportSales, parseTime("2019-09-09"), Daily, at.Add(-time.Second)) != true {
+		t.Error("yesterday should be published")
+	}
+}
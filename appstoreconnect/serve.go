@@ -0,0 +1,211 @@
+package appstoreconnect
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// Serving reports to tools which should not hold the api key themselves. A ReportServer answers
+// requests with its own api tokens, fetches through its Client and so its cache, and makes a
+// single call to apple for identical requests arriving together.
+
+// ReportServer serves reports over http
+//
+//	GET /sales?date=2024-05&type=SALES&sub_type=SUMMARY&vendor=91032757&format=csv
+//	GET /finance?month=2024-04&region=US&format=json
+//
+// date and month take any range expression, like 2024-01:2024-03 or last-7d, and vendor a comma
+// separated list. Requests must carry "Authorization: Bearer <token>" with one of the server's
+// tokens. When the range reaches past what apple has published the rows available are served
+// with an X-Not-Yet-Published header. Requests for more than MaxPeriods reports are refused.
+type ReportServer struct {
+	// MaxPeriods is the most reports, periods times vendors, one request may fetch
+	MaxPeriods int
+
+	client *Client
+	tokens [][sha256.Size]byte
+	mux    *http.ServeMux
+
+	group flightGroup
+}
+
+// DefaultMaxPeriods allows a year of daily reports per request
+const DefaultMaxPeriods = 366
+
+// NewReportServer creates a server fetching reports with client for requests with one of tokens
+func NewReportServer(client *Client, tokens ...string) *ReportServer {
+	s := &ReportServer{MaxPeriods: DefaultMaxPeriods, client: client, mux: http.NewServeMux()}
+	for _, token := range tokens {
+		if token != "" {
+			s.tokens = append(s.tokens, sha256.Sum256([]byte(token)))
+		}
+	}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.HandleFunc("GET /sales", s.authorized(s.serveSales))
+	s.mux.HandleFunc("GET /finance", s.authorized(s.serveFinance))
+	return s
+}
+
+// ServeHTTP serves reports and /healthz, which needs no token
+func (s *ReportServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized rejects requests without one of the server's tokens, comparing digests so the time
+// taken tells nothing about the tokens
+func (s *ReportServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		digest := sha256.Sum256([]byte(bearer))
+		valid := 0
+		for _, token := range s.tokens {
+			valid |= subtle.ConstantTimeCompare(digest[:], token[:])
+		}
+		if !ok || valid == 0 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reports"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid api token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *ReportServer) serveSales(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	reportType := ReportType(queryOr(q.Get("type"), string(ReportSales)))
+	reportSubType := ReportSubType(queryOr(q.Get("sub_type"), string(SubReportSummary)))
+	if reportType == ReportFinancial {
+		writeError(w, http.StatusBadRequest, errors.New("use /finance for finance reports"))
+		return
+	}
+	tr, err := (&RangeParser{Now: s.client.clock, ReportType: reportType}).Parse(q.Get("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	vendors := queryList(q.Get("vendor"))
+	if err := s.checkSize(tr, vendors); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key := strings.Join([]string{"sales", rangeKey(tr), string(reportType), string(reportSubType), strings.Join(vendors, ",")}, "/")
+	s.serve(w, r, key, func() (encoding.Encodable, error) {
+		switch len(vendors) {
+		case 0:
+			return s.client.SalesReport.GetRange(tr, reportType, reportSubType)
+		case 1:
+			return s.client.WithVendor(vendors[0]).SalesReport.GetRange(tr, reportType, reportSubType)
+		}
+		return s.client.SalesReport.GetVendors(vendors, tr, reportType, reportSubType)
+	})
+}
+
+func (s *ReportServer) serveFinance(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	region := queryOr(q.Get("region"), "US")
+	tr, err := (&RangeParser{Now: s.client.clock, ReportType: ReportFinancial}).Parse(q.Get("month"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	vendors := queryList(q.Get("vendor"))
+	if err := s.checkSize(tr, vendors); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key := strings.Join([]string{"finance", rangeKey(tr), region, strings.Join(vendors, ",")}, "/")
+	s.serve(w, r, key, func() (encoding.Encodable, error) {
+		switch len(vendors) {
+		case 0:
+			return s.client.FinanceReport.GetRange(tr, region)
+		case 1:
+			return s.client.WithVendor(vendors[0]).FinanceReport.GetRange(tr, region)
+		}
+		return s.client.FinanceReport.GetVendors(vendors, tr, region)
+	})
+}
+
+// checkSize refuses ranges which would take more than MaxPeriods requests to apple
+func (s *ReportServer) checkSize(tr *TimeRange, vendors []string) error {
+	n := tr.Len() * max(len(vendors), 1)
+	if s.MaxPeriods > 0 && n > s.MaxPeriods {
+		return errors.New("range needs " + strconv.Itoa(n) + " reports, more than " + strconv.Itoa(s.MaxPeriods) + ": use a coarser frequency or a shorter range")
+	}
+	return nil
+}
+
+// serve writes the report fetched by fetch, shared with identical requests in flight, in the
+// requested format
+func (s *ReportServer) serve(w http.ResponseWriter, r *http.Request, key string, fetch func() (encoding.Encodable, error)) {
+	format := encoding.Json
+	if f := r.URL.Query().Get("format"); f != "" {
+		if err := format.Set(f); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	report, err := s.group.do(key, fetch)
+	if errors.Is(err, ErrNotYetPublished) && report != nil {
+		w.Header().Set("X-Not-Yet-Published", "true")
+		err = nil
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	b, err := report.ToEncoding(format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Write(b)
+}
+
+var contentTypes = map[encoding.Encoding]string{
+	encoding.Json: "application/json",
+	encoding.Csv:  "text/csv; charset=utf-8",
+	encoding.Tsv:  "text/tab-separated-values; charset=utf-8",
+}
+
+// rangeKey identifies the periods of tr, however they were written in the request
+func rangeKey(tr *TimeRange) string {
+	return tr.Start.Format("2006-01-02") + ":" + tr.End.Format("2006-01-02") + ":" + string(tr.Frequency)
+}
+
+func queryOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func queryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,102 @@
+package appstoreconnect_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+func TestReportServer(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+	server.AddFinanceReport("2024-04", "US", financeTsv)
+	h := appstoreconnect.NewReportServer(client, "secret", "other")
+
+	get := func(path string, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, token := range []string{"", "wrong", "secre"} {
+		if w := get("/sales?date=2024-05-01", token); w.Code != http.StatusUnauthorized {
+			t.Error("expected the token to be rejected", token, w.Code)
+		}
+	}
+	if w := get("/healthz", ""); w.Code != http.StatusOK {
+		t.Error("expected health to need no token", w.Code)
+	}
+
+	w := get("/sales?date=2024-05-01&type=SALES&format=csv", "secret")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" ||
+		!strings.Contains(w.Body.String(), "com.example.app") {
+		t.Error("unexpected sales", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = get("/finance?month=2024-04&region=US", "other")
+	finance := appstoreconnect.FinanceReportResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&finance); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(finance.Reports) != 1 || finance.Reports[0].VendorIdentifier != "com.example.app" {
+		t.Errorf("unexpected finance %d %+v", w.Code, finance)
+	}
+
+	for _, path := range []string{"/sales", "/sales?date=tomorrowish", "/sales?date=2024-05-01&format=xml", "/sales?date=2024-05&type=FINANCIAL"} {
+		if w := get(path, "secret"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"error"`) {
+			t.Error("expected a bad request", path, w.Code)
+		}
+	}
+
+	// long ranges are refused before asking apple for anything
+	requests := len(server.Requests())
+	for _, path := range []string{"/sales?date=2020-01-01:2024-05-01", "/sales?date=2023-12-01:2024-05-01&vendor=1,2,3", "/finance?month=1990-01:2024-04"} {
+		if w := get(path, "secret"); w.Code != http.StatusBadRequest {
+			t.Error("expected the range to be refused", path, w.Code)
+		}
+	}
+	if len(server.Requests()) != requests {
+		t.Error("expected no requests for refused ranges")
+	}
+
+	// and counted rather than iterated, however long
+	start := time.Now()
+	for _, path := range []string{"/sales?date=last-100000d", "/sales?date=0001-01-01:9999-12-31", "/sales?date=0001-01-01:9999-12-31&vendor=1,2,3", "/finance?month=0001-01:9999-12"} {
+		if w := get(path, "secret"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "reports, more than") {
+			t.Error("expected the range to be refused", path, w.Code, w.Body.String())
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Error("expected huge ranges to be refused quickly", elapsed)
+	}
+
+	// apple's errors are passed on as a bad gateway
+	server.Fail(http.StatusInternalServerError, 10)
+	if w := get("/sales?date=2024-05-02", "secret"); w.Code != http.StatusBadGateway {
+		t.Error("expected a bad gateway", w.Code)
+	}
+}
+
+func TestReportServerNotYetPublished(t *testing.T) {
+	server, client := newServerClient(t)
+	server.AddSalesReport(appstoreconnect.Daily, "2024-07-30", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+	h := appstoreconnect.NewReportServer(client, "secret")
+
+	// the 31st is not published until the morning of august 1st
+	r := httptest.NewRequest(http.MethodGet, "/sales?date=2024-07-30:2024-07-31&format=tsv", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("X-Not-Yet-Published") != "true" || !strings.Contains(w.Body.String(), "com.example.app") {
+		t.Error("expected the published rows", w.Code, w.Header(), w.Body.String())
+	}
+}
@@ -0,0 +1,286 @@
+package appstoreconnect
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// Incremental sync of reports. A Syncer remembers the last period it fetched for each report
+// stream and only asks Apple for what is newly published, plus a trailing window of periods
+// Apple is known to restate.
+
+var (
+	// ErrSyncStartRequired a stream has no checkpoint and the Syncer has no Start date
+	ErrSyncStartRequired = errors.New("sync: no checkpoint and no start date")
+)
+
+// SyncKey identifies one stream of reports tracked by a Syncer
+type SyncKey struct {
+	VendorNumber  string        `json:"vendor_number"`
+	ReportType    ReportType    `json:"report_type"`
+	ReportSubType ReportSubType `json:"report_sub_type,omitempty"`
+	Frequency     Frequency     `json:"frequency"`
+	RegionCode    string        `json:"region_code,omitempty"`
+}
+
+// Checkpoint records the progress of a SyncKey
+type Checkpoint struct {
+	Key       SyncKey   `json:"key"`
+	Last      time.Time `json:"last"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists checkpoints between runs
+type CheckpointStore interface {
+	// Load returns the checkpoint for key or nil if the stream has never been synced
+	Load(key SyncKey) (*Checkpoint, error)
+	Save(cp *Checkpoint) error
+}
+
+// SyncFunc receives each report fetched by a Syncer. report is a *SalesReportResponse or
+// a *FinanceReportResponse depending on the key.
+type SyncFunc func(key SyncKey, period time.Time, report encoding.Encodable) error
+
+// RawSyncFunc receives each report file fetched by a Syncer as apple served it
+type RawSyncFunc func(key SyncKey, period time.Time, raw *RawReport) error
+
+// Syncer fetches only the periods of a report stream which are missing or may have been revised
+type Syncer struct {
+	client *Client
+	store  CheckpointStore
+
+	// Start is the first period to fetch for streams without a checkpoint
+	Start time.Time
+
+	// RestateWindow is how many already synced periods to fetch again, by frequency.
+	// nil uses DefaultRestateWindow.
+	RestateWindow map[Frequency]int
+
+	// Now is the clock used to decide which periods Apple has published
+	Now func() time.Time
+}
+
+// DefaultRestateWindow is the number of trailing periods Apple may still revise after publishing
+var DefaultRestateWindow = map[Frequency]int{
+	Daily:         3,
+	Weekly:        1,
+	Monthly:       1,
+	FiscalMonthly: 1,
+	Yearly:        0,
+}
+
+// NewSyncer creates a Syncer which fetches with client and records progress in store
+func NewSyncer(client *Client, store CheckpointStore) *Syncer {
+	return &Syncer{
+		client: client,
+		store:  store,
+		Now:    time.Now,
+	}
+}
+
+// Pending returns the periods of key which the next Sync would fetch
+func (s *Syncer) Pending(key SyncKey) (*TimeRange, error) {
+	key = s.normalize(key)
+	cp, err := s.store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := LatestAvailable(key.ReportType, key.Frequency, s.Now())
+
+	var from time.Time
+	if cp == nil {
+		if s.Start.IsZero() {
+			return nil, ErrSyncStartRequired
+		}
+		from = s.Start
+	} else {
+		from = addFrequency(cp.Last, key.Frequency)
+		for i := 0; i < s.restate(key.Frequency); i++ {
+			from = prevPeriod(from, key.Frequency)
+		}
+	}
+
+	return NewTimeRange(from, latest, key.Frequency), nil
+}
+
+// Sync fetches every pending period of key in order, hands it to fn and checkpoints after each
+// one, so an interrupted sync resumes where it stopped
+func (s *Syncer) Sync(key SyncKey, fn SyncFunc) error {
+	return s.sync(key, func(key SyncKey, period time.Time, report encoding.Encodable, _ *RawReport) error {
+		return fn(key, period, report)
+	})
+}
+
+// SyncRaw is Sync handing fn apple's report files rather than parsed reports
+func (s *Syncer) SyncRaw(key SyncKey, fn RawSyncFunc) error {
+	return s.sync(key, func(key SyncKey, period time.Time, _ encoding.Encodable, raw *RawReport) error {
+		return fn(key, period, raw)
+	})
+}
+
+func (s *Syncer) sync(key SyncKey, fn func(SyncKey, time.Time, encoding.Encodable, *RawReport) error) error {
+	key = s.normalize(key)
+	tr, err := s.Pending(key)
+	if err != nil {
+		return err
+	}
+
+	cp, err := s.store.Load(key)
+	if err != nil {
+		return err
+	}
+	if cp == nil {
+		cp = &Checkpoint{Key: key}
+	}
+
+	for p := range tr.Periods() {
+		period := p.Date
+		report, raw, err := s.fetch(key, period)
+		if err != nil && err != ErrNoData {
+			return err
+		}
+		if err == nil {
+			if err := fn(key, period, report, raw); err != nil {
+				return err
+			}
+		}
+
+		// restated periods must not move the checkpoint backwards
+		if period.After(cp.Last) {
+			cp.Last = period
+		}
+		cp.UpdatedAt = s.Now()
+		if err := s.store.Save(cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) fetch(key SyncKey, period time.Time) (encoding.Encodable, *RawReport, error) {
+	client := s.client
+	if key.VendorNumber != client.vendorNumber {
+		client = client.WithVendor(key.VendorNumber)
+	}
+	if key.ReportType == ReportFinancial {
+		return fetched(client.FinanceReport.GetRaw(period, key.RegionCode))
+	}
+	return fetched(client.SalesReport.GetRaw(period, key.Frequency, key.ReportType, key.ReportSubType))
+}
+
+// fetched keeps a nil report nil once it is an encoding.Encodable
+func fetched[R encoding.Encodable](report R, raw *RawReport, err error) (encoding.Encodable, *RawReport, error) {
+	if err != nil {
+		return nil, raw, err
+	}
+	return report, raw, nil
+}
+
+func (s *Syncer) normalize(key SyncKey) SyncKey {
+	if key.VendorNumber == "" {
+		key.VendorNumber = s.client.vendorNumber
+	}
+	if key.ReportType == ReportFinancial {
+		key.Frequency = FiscalMonthly
+	}
+	return key
+}
+
+func (s *Syncer) restate(f Frequency) int {
+	w := s.RestateWindow
+	if w == nil {
+		w = DefaultRestateWindow
+	}
+	return w[f]
+}
+
+// String is the stable form of the key used by stores
+func (k SyncKey) String() string {
+	return strings.Join([]string{
+		k.VendorNumber,
+		string(k.ReportType),
+		string(k.ReportSubType),
+		string(k.Frequency),
+		k.RegionCode,
+	}, "/")
+}
+
+// FileCheckpointStore keeps all checkpoints in a single json file
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a store backed by the json file at path, which need not exist yet
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load returns the checkpoint for key or nil if there is none
+func (f *FileCheckpointStore) Load(key SyncKey) (*Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return all[key.String()], nil
+}
+
+// Save writes cp, replacing the file atomically so a crash never leaves it half written
+func (f *FileCheckpointStore) Save(cp *Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return err
+	}
+	all[cp.Key.String()] = cp
+
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *FileCheckpointStore) read() (map[string]*Checkpoint, error) {
+	all := map[string]*Checkpoint{}
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &all)
+	return all, err
+}
@@ -0,0 +1,55 @@
+package appstoreconnect
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncPending(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "sync.json"))
+	s := NewSyncer(&Client{vendorNumber: "123"}, store)
+	s.Now = func() time.Time { return time.Date(2019, 9, 10, 16, 0, 0, 0, time.UTC) }
+	key := SyncKey{ReportType: ReportSales, ReportSubType: SubReportSummary, Frequency: Daily}
+
+	if _, err := s.Pending(key); err != ErrSyncStartRequired {
+		t.Error("expected start to be required without a checkpoint")
+	}
+
+	s.Start = parseTime("2019-09-05")
+	tims := readTimes(mustPending(t, s, key))
+	if len(tims) != 5 || !tims[0].Equal(s.Start) || !tims[4].Equal(parseTime("2019-09-09")) {
+		t.Error("unexpected first sync periods", tims)
+	}
+
+	key.VendorNumber = "123"
+	err := store.Save(&Checkpoint{Key: key, Last: parseTime("2019-09-09")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing new, only the restate window
+	expected := []time.Time{
+		parseTime("2019-09-07"),
+		parseTime("2019-09-08"),
+		parseTime("2019-09-09"),
+	}
+	tims = readTimes(mustPending(t, s, key))
+	if !reflect.DeepEqual(expected, tims) {
+		t.Error("unexpected restated periods", tims)
+	}
+
+	s.RestateWindow = map[Frequency]int{}
+	if tims = readTimes(mustPending(t, s, key)); len(tims) != 0 {
+		t.Error("expected nothing pending", tims)
+	}
+}
+
+func mustPending(t *testing.T, s *Syncer, key SyncKey) *TimeRange {
+	tr, err := s.Pending(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
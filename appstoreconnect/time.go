@@ -5,13 +5,23 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	_ "time/tzdata" // reporting days are defined in Pacific Time regardless of host zoneinfo
 )
 
+// Report dates are calendar dates. Only the year, month and day of a time.Time are used, in
+// whatever location it carries, and dates are kept as midnight UTC so that day arithmetic never
+// crosses a DST transition. The current date is always taken in ReportingLocation, the time zone
+// Apple's reporting day is defined in, rather than the host's local time.
+
 var (
 	// ErrTimeFormatInvalid bad date string
 	ErrTimeFormatInvalid = errors.New("timerange: invalid format")
 )
 
+// ReportingLocation is the time zone which decides what day it is. Apple's reporting day and
+// publishing schedule are in Pacific Time.
+var ReportingLocation = mustLoadLocation("America/Los_Angeles")
+
 type Frequency string
 
 type TimeRange struct {
@@ -27,33 +37,10 @@ func NewTime(timeString string) (time.Time, error) {
 	return t, err
 }
 
+// ParseTimeRange parses a date or range of dates, see RangeParser for the accepted formats
 func ParseTimeRange(value string) (*TimeRange, error) {
-	if value == "" {
-		return nil, ErrTimeFormatInvalid
-	}
-
-	parts := strings.Split(value, ":")
-	t1, f, err := parseTimeAndFrequency(parts[0])
-	if err != nil {
-		return nil, err
-	}
-
-	var t2 time.Time
-	if len(parts) > 1 {
-		t2, _, err = parseTimeAndFrequency(parts[1])
-		if err != nil {
-			return nil, err
-		}
-		if t1.After(t2) {
-			temp := t1
-			t1 = t2
-			t2 = temp
-		}
-	} else {
-		t2 = t1
-	}
-
-	return NewTimeRange(t1, t2, f), nil
+	p := RangeParser{}
+	return p.Parse(value)
 }
 
 func NewSingleTimeRange(timeString string, frequency Frequency) (*TimeRange, error) {
@@ -72,11 +59,13 @@ func NewTimeRange(start time.Time, end time.Time, frequency Frequency) *TimeRang
 		current:   start,
 		index:     0,
 	}
-	t.Start = roundDown(start, frequency)
-	t.End = roundDown(end, frequency)
+	t.Start = roundDown(civilDate(start), frequency)
+	t.End = roundDown(civilDate(end), frequency)
 	return &t
 }
 
+// Next advances to the next period, returning false once past the end. Prefer Periods, which
+// leaves the range untouched and is safe to use from several goroutines.
 func (t *TimeRange) Next() bool {
 	var next time.Time
 	if t.index == 0 {
@@ -92,22 +81,26 @@ func (t *TimeRange) Next() bool {
 		return true
 	}
 
+	// start over so the range can be iterated again
+	t.index = 0
 	return false
 }
 
+// Current is the period Next advanced to
 func (t *TimeRange) Current() time.Time {
 	return t.current
 }
 
 func timeToReportDate(t time.Time, f Frequency) string {
+	t = civilDate(t)
 	var format string
 	switch f {
 	case Daily:
 		format = "2006-01-02"
 	case Weekly:
-		t = t.AddDate(0, 0, -int(t.Weekday()))
+		t = weekEnding(t)
 		format = "2006-01-02"
-	case Monthly:
+	case Monthly, FiscalMonthly:
 		format = "2006-01"
 	case Yearly:
 		format = "2006"
@@ -117,15 +110,28 @@ func timeToReportDate(t time.Time, f Frequency) string {
 	return t.Format(format)
 }
 
+// parseReportDate is the inverse of timeToReportDate
+func parseReportDate(value string, f Frequency) (time.Time, error) {
+	var format string
+	switch f {
+	case Monthly, FiscalMonthly:
+		format = "2006-01"
+	case Yearly:
+		format = "2006"
+	default:
+		format = "2006-01-02"
+	}
+	return time.Parse(format, value)
+}
+
 func addFrequency(t time.Time, f Frequency) time.Time {
 	var r time.Time
 	switch f {
 	case Daily:
 		r = t.AddDate(0, 0, 1)
 	case Weekly:
-		r = t.AddDate(0, 0, -int(t.Weekday()))
-		r = r.AddDate(0, 0, 7)
-	case Monthly:
+		r = weekEnding(t).AddDate(0, 0, 7)
+	case Monthly, FiscalMonthly:
 		r = t.AddDate(0, 1, 0)
 	case Yearly:
 		r = t.AddDate(1, 0, 0)
@@ -133,18 +139,36 @@ func addFrequency(t time.Time, f Frequency) time.Time {
 	return r
 }
 
+// roundDown returns the value identifying the period containing t. Weekly periods run monday
+// through sunday and, as in Apple's weekly reports, are identified by the sunday ending them.
 func roundDown(t time.Time, f Frequency) time.Time {
 	switch f {
 	case Daily:
 	case Weekly:
-		t = t.AddDate(0, 0, -int(t.Weekday()))
-	case Monthly:
+		t = weekEnding(t)
+	case Monthly, FiscalMonthly:
 		t = t.AddDate(0, 0, -int(t.Day())+1)
 	case Yearly:
+		t = t.AddDate(0, 1-int(t.Month()), 1-t.Day())
 	}
 	return t
 }
 
+// weekEnding returns the sunday ending the monday to sunday week containing t
+func weekEnding(t time.Time) time.Time {
+	return t.AddDate(0, 0, (7-int(t.Weekday()))%7)
+}
+
+// parseTimeAndFrequency parses one of
+//
+//	2019        a year
+//	2019-09     a month
+//	2019-09-08  a day
+//	2019-W36    an ISO 8601 week, monday through sunday
+//	2019-09-w2  the week ending on the 2nd sunday of september
+//	2019-09-15w the week ending on sunday the 15th
+//
+// Weeks are returned as the sunday ending them.
 func parseTimeAndFrequency(value string) (time.Time, Frequency, error) {
 	parts := strings.Split(value, "-")
 	switch len(parts) {
@@ -152,17 +176,22 @@ func parseTimeAndFrequency(value string) (time.Time, Frequency, error) {
 		t, err := time.Parse("2006", value)
 		return t, Yearly, err
 	case 2:
+		if strings.HasPrefix(parts[1], "W") || strings.HasPrefix(parts[1], "w") {
+			t, err := parseISOWeek(value)
+			return t, Weekly, err
+		}
 		t, err := time.Parse("2006-01", value)
 		return t, Monthly, err
 	case 3:
 		if strings.HasPrefix(parts[2], "w") {
-			var year, month, week int
-			_, err := fmt.Sscanf(value, "%d-%d-w%d", &year, &month, &week)
-			if err != nil {
+			t, err := parseWeekOfMonth(value)
+			return t, Weekly, err
+		}
+		if day, ok := strings.CutSuffix(value, "w"); ok {
+			t, err := time.Parse("2006-01-02", day)
+			if err != nil || t.Weekday() != time.Sunday {
 				return time.Time{}, Weekly, ErrTimeFormatInvalid
 			}
-			t := time.Date(year, time.Month(month), week, 0, 0, 0, 0, time.UTC)
-			t = t.AddDate(0, 0, week*7)
 			return t, Weekly, nil
 		}
 		t, err := time.Parse("2006-01-02", value)
@@ -171,11 +200,67 @@ func parseTimeAndFrequency(value string) (time.Time, Frequency, error) {
 	return time.Time{}, Daily, ErrTimeFormatInvalid
 }
 
+// parseISOWeek parses 2019-W36 into the sunday ending that week
+func parseISOWeek(value string) (time.Time, error) {
+	var year, week int
+	var w rune
+	n, err := fmt.Sscanf(value, "%4d-%c%d", &year, &w, &week)
+	if err != nil || n != 3 || (w != 'W' && w != 'w') {
+		return time.Time{}, ErrTimeFormatInvalid
+	}
+
+	// week 1 is the week with january 4th in it
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	t := weekEnding(jan4).AddDate(0, 0, (week-1)*7)
+
+	y, wk := t.ISOWeek()
+	if week < 1 || y != year || wk != week {
+		return time.Time{}, ErrTimeFormatInvalid
+	}
+	return t, nil
+}
+
+// parseWeekOfMonth parses 2019-09-w2 into the 2nd sunday of september
+func parseWeekOfMonth(value string) (time.Time, error) {
+	var year, month, week int
+	n, err := fmt.Sscanf(value, "%4d-%2d-w%d", &year, &month, &week)
+	if err != nil || n != 3 || month < 1 || month > 12 || week < 1 {
+		return time.Time{}, ErrTimeFormatInvalid
+	}
+
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	t := weekEnding(first).AddDate(0, 0, (week-1)*7)
+	if t.Month() != first.Month() {
+		return time.Time{}, ErrTimeFormatInvalid
+	}
+	return t, nil
+}
+
+// Yesterday is a daily range of the previous reporting day
 func Yesterday() *TimeRange {
-	t := time.Now().AddDate(0, 0, -1)
-	return &TimeRange{
-		Start:     t,
-		End:       t,
-		Frequency: Daily,
+	t := Today().AddDate(0, 0, -1)
+	return NewTimeRange(t, t, Daily)
+}
+
+// Today returns the current date in ReportingLocation
+func Today() time.Time {
+	return ReportingDay(time.Now())
+}
+
+// ReportingDay returns the date in ReportingLocation at the instant t
+func ReportingDay(t time.Time) time.Time {
+	return civilDate(t.In(ReportingLocation))
+}
+
+// civilDate drops the time of day and location of t, keeping its date
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
 	}
+	return loc
 }
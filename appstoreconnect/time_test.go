@@ -15,8 +15,8 @@ func parseTime(value string) time.Time {
 }
 
 func TestWeekly(t *testing.T) {
+	// weeks end on sunday
 	expected := []time.Time{
-		parseTime("2019-09-08"),
 		parseTime("2019-09-15"),
 		parseTime("2019-09-22"),
 		parseTime("2019-09-29"),
@@ -24,6 +24,7 @@ func TestWeekly(t *testing.T) {
 		parseTime("2019-10-13"),
 		parseTime("2019-10-20"),
 		parseTime("2019-10-27"),
+		parseTime("2019-11-03"),
 	}
 
 	tims := readTimes(NewTimeRange(parseTime("2019-09-10"), parseTime("2019-10-31"), Weekly))
@@ -34,7 +35,7 @@ func TestWeekly(t *testing.T) {
 
 func TestOneWeekly(t *testing.T) {
 	expected := []time.Time{
-		parseTime("2019-09-08"),
+		parseTime("2019-09-15"),
 	}
 	tims := readTimes(NewTimeRange(parseTime("2019-09-10"), parseTime("2019-09-10"), Weekly))
 	if !reflect.DeepEqual(expected, tims) {
@@ -42,6 +43,61 @@ func TestOneWeekly(t *testing.T) {
 	}
 }
 
+func TestWeeklyYearBoundary(t *testing.T) {
+	expected := []time.Time{
+		parseTime("2019-12-29"),
+		parseTime("2020-01-05"),
+		parseTime("2020-01-12"),
+	}
+
+	tims := readTimes(NewTimeRange(parseTime("2019-12-23"), parseTime("2020-01-06"), Weekly))
+	if !reflect.DeepEqual(expected, tims) {
+		t.Error("Weekly not what I was expecting", tims)
+	}
+
+	if d := timeToReportDate(parseTime("2019-12-30"), Weekly); d != "2020-01-05" {
+		t.Error("unexpected weekly report date: " + d)
+	}
+}
+
+func TestParseWeek(t *testing.T) {
+	valid := map[string]string{
+		"2019-W36":    "2019-09-08",
+		"2019-w01":    "2019-01-06",
+		"2020-W01":    "2020-01-05",
+		"2020-W53":    "2021-01-03",
+		"2015-W01":    "2015-01-04",
+		"2019-09-w1":  "2019-09-01",
+		"2019-09-w5":  "2019-09-29",
+		"2019-12-29w": "2019-12-29",
+	}
+	for value, expected := range valid {
+		tm, f, err := parseTimeAndFrequency(value)
+		if err != nil {
+			t.Error(value, err)
+			continue
+		}
+		if f != Weekly || !tm.Equal(parseTime(expected)) {
+			t.Error("unexpected week for " + value + ": " + tm.String())
+		}
+	}
+
+	invalid := []string{
+		"2019-W00",
+		"2019-W53",
+		"2019-Wxx",
+		"2019-09-w0",
+		"2019-10-w5",
+		"2019-13-w1",
+		"2019-12-28w",
+	}
+	for _, value := range invalid {
+		if _, _, err := parseTimeAndFrequency(value); err == nil {
+			t.Error("expected invalid week: " + value)
+		}
+	}
+}
+
 func TestDaily(t *testing.T) {
 	expected := []time.Time{
 		parseTime("2018-12-29"),
@@ -359,3 +415,276 @@ func readTimes(itr *TimeRange) []time.Time {
 	}
 	return tims
 }
+
+func TestFiscalCalendar(t *testing.T) {
+	c := AppleFiscalCalendar
+	if !c.YearStart(2024).Equal(parseTime("2023-10-01")) || !c.YearEnd(2024).Equal(parseTime("2024-09-28")) {
+		t.Error("unexpected fiscal year 2024 bounds")
+	}
+
+	periods := c.Periods(2024)
+	if !periods[0].End.Equal(parseTime("2023-11-04")) {
+		t.Error("unexpected end of october: " + periods[0].End.String())
+	}
+	jan := periods[3]
+	if !jan.Start.Equal(parseTime("2023-12-31")) || !jan.End.Equal(parseTime("2024-02-03")) || !jan.Month().Equal(parseTime("2024-01")) {
+		t.Errorf("unexpected fiscal january %+v", jan)
+	}
+	if !periods[11].End.Equal(c.YearEnd(2024)) {
+		t.Error("periods should cover the fiscal year")
+	}
+
+	// 53 week year, the extra week is in october
+	p := c.PeriodOf(parseTime("2022-09-25"))
+	if p.Year != 2023 || p.Period != 1 || !p.End.Equal(parseTime("2022-11-05")) {
+		t.Errorf("unexpected 53 week year period %+v", p)
+	}
+
+	p = c.PeriodOf(parseTime("2024-03-02"))
+	if p.Period != 5 || !p.Month().Equal(parseTime("2024-02")) || !p.Contains(parseTime("2024-02-04")) {
+		t.Errorf("unexpected period for march 2nd %+v", p)
+	}
+	if c.PeriodNamed(parseTime("2023-10")) != c.Periods(2024)[0] {
+		t.Error("october 2023 should name the first period of fiscal 2024")
+	}
+
+	expected := []time.Time{
+		parseTime("2024-02-01"),
+		parseTime("2024-03-01"),
+	}
+	tims := readTimes(NewTimeRange(parseTime("2024-03-02"), parseTime("2024-03-03"), Daily).Fiscal(c))
+	if !reflect.DeepEqual(expected, tims) {
+		t.Error("unexpected fiscal range", tims)
+	}
+}
+
+func TestRangeParser(t *testing.T) {
+	// wednesday 2019-09-11 at 01:00 UTC is still tuesday the 10th in Pacific Time
+	p := RangeParser{Now: func() time.Time { return time.Date(2019, 9, 11, 1, 0, 0, 0, time.UTC) }}
+
+	tests := []struct {
+		expr       string
+		start, end string
+		frequency  Frequency
+	}{
+		{"yesterday", "2019-09-09", "2019-09-09", Daily},
+		{"last-7d", "2019-09-03", "2019-09-09", Daily},
+		{"mtd", "2019-09-01", "2019-09-09", Daily},
+		{"ytd", "2019-01-01", "2019-09-09", Daily},
+		{"last-week", "2019-09-08", "2019-09-08", Weekly},
+		{"last-2w", "2019-09-01", "2019-09-08", Weekly},
+		{"last-month", "2019-08-01", "2019-08-01", Monthly},
+		{"last-3m", "2019-06-01", "2019-08-01", Monthly},
+		{"last-100000d", "1745-11-25", "2019-09-09", Daily},
+		{"last-year", "2018-01-01", "2018-01-01", Yearly},
+		{"last-fiscal-period", "2019-08-01", "2019-08-01", FiscalMonthly},
+		{"2019-08-25:yesterday", "2019-08-25", "2019-09-09", Daily},
+		{"2019-06:", "2019-06-01", "2019-08-01", Monthly},
+		{"2019-09-01:", "2019-09-01", "2019-09-09", Daily},
+		{"2018:2019-06", "2018-01-01", "2019-01-01", Yearly},
+	}
+	for _, test := range tests {
+		tr, err := p.Parse(test.expr)
+		if err != nil {
+			t.Error(test.expr, err)
+			continue
+		}
+		if !tr.Start.Equal(parseTime(test.start)) || !tr.End.Equal(parseTime(test.end)) || tr.Frequency != test.frequency {
+			t.Errorf("%s: unexpected range %s:%s %s", test.expr, tr.Start, tr.End, tr.Frequency)
+		}
+	}
+
+	for _, expr := range []string{"last-0d", "last-d", "tomorrow", "2019-06:nope", "last-100001d", "last-999999999999d"} {
+		if _, err := p.Parse(expr); err == nil {
+			t.Error("expected invalid expression: " + expr)
+		}
+	}
+}
+
+func TestRangeParserFirstDay(t *testing.T) {
+	// the first of the month and year have no complete day to date, so the previous one is used
+	at := func(instant time.Time) RangeParser {
+		return RangeParser{Now: func() time.Time { return instant }}
+	}
+
+	tests := []struct {
+		parser     RangeParser
+		expr       string
+		start, end string
+	}{
+		{at(time.Date(2019, 9, 1, 12, 0, 0, 0, ReportingLocation)), "mtd", "2019-08-01", "2019-08-31"},
+		{at(time.Date(2019, 9, 1, 12, 0, 0, 0, ReportingLocation)), "ytd", "2019-01-01", "2019-08-31"},
+		{at(time.Date(2020, 1, 1, 12, 0, 0, 0, ReportingLocation)), "mtd", "2019-12-01", "2019-12-31"},
+		{at(time.Date(2020, 1, 1, 12, 0, 0, 0, ReportingLocation)), "ytd", "2019-01-01", "2019-12-31"},
+		{at(time.Date(2020, 3, 1, 12, 0, 0, 0, ReportingLocation)), "mtd", "2020-02-01", "2020-02-29"},
+		// 2020-01-01 07:00 UTC is still new year's eve in Pacific Time
+		{at(time.Date(2020, 1, 1, 7, 0, 0, 0, time.UTC)), "ytd", "2019-01-01", "2019-12-30"},
+	}
+	for _, test := range tests {
+		tr, err := test.parser.Parse(test.expr)
+		if err != nil {
+			t.Error(test.expr, err)
+			continue
+		}
+		if !tr.Start.Equal(parseTime(test.start)) || !tr.End.Equal(parseTime(test.end)) || tr.Len() == 0 {
+			t.Errorf("%s: unexpected range %s:%s", test.expr, tr.Start, tr.End)
+		}
+	}
+}
+
+func TestReportingDay(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	// 09:00 on the 10th in Tokyo is still the 9th in Pacific Time
+	if d := ReportingDay(time.Date(2019, 9, 10, 9, 0, 0, 0, tokyo)); !d.Equal(parseTime("2019-09-09")) {
+		t.Error("unexpected reporting day: " + d.String())
+	}
+
+	// either side of the spring forward and fall back transitions
+	days := map[time.Time]string{
+		time.Date(2019, 3, 10, 7, 59, 0, 0, time.UTC):  "2019-03-09",
+		time.Date(2019, 3, 10, 10, 0, 0, 0, time.UTC):  "2019-03-10",
+		time.Date(2019, 11, 3, 6, 59, 0, 0, time.UTC):  "2019-11-02",
+		time.Date(2019, 11, 4, 7, 59, 0, 0, time.UTC):  "2019-11-03",
+		time.Date(2019, 11, 4, 8, 0, 0, 0, time.UTC):   "2019-11-04",
+		time.Date(2019, 11, 3, 23, 30, 0, 0, tokyo):    "2019-11-03",
+		time.Date(2019, 3, 10, 23, 59, 0, 0, time.UTC): "2019-03-10",
+	}
+	for instant, expected := range days {
+		if d := ReportingDay(instant); !d.Equal(parseTime(expected)) {
+			t.Error("unexpected reporting day for " + instant.String() + ": " + d.String())
+		}
+	}
+}
+
+func TestTimeRangeAcrossDST(t *testing.T) {
+	loc := ReportingLocation
+	expected := []string{"2019-03-09", "2019-03-10", "2019-03-11"}
+
+	tr := NewTimeRange(time.Date(2019, 3, 9, 23, 0, 0, 0, loc), time.Date(2019, 3, 11, 0, 30, 0, 0, loc), Daily)
+	var dates []string
+	for tr.Next() {
+		dates = append(dates, timeToReportDate(tr.Current(), Daily))
+	}
+	if !reflect.DeepEqual(expected, dates) {
+		t.Error("unexpected dates across DST", dates)
+	}
+
+	y := Yesterday()
+	if !y.Next() || !y.Current().Equal(Today().AddDate(0, 0, -1)) || y.Next() {
+		t.Error("yesterday should be a single day")
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	tr := NewTimeRange(parseTime("2019-09-10"), parseTime("2019-09-24"), Weekly)
+
+	var periods []Period
+	for p := range tr.Periods() {
+		periods = append(periods, p)
+	}
+	if len(periods) != 3 || tr.Len() != 3 {
+		t.Fatal("expected three weeks", periods)
+	}
+	if !periods[0].Start.Equal(parseTime("2019-09-09")) || !periods[0].End.Equal(parseTime("2019-09-15")) || !periods[0].Date.Equal(periods[0].End) {
+		t.Errorf("unexpected first week %+v", periods[0])
+	}
+
+	// iterating does not use the range up
+	if len(readTimes(tr)) != 3 || len(readTimes(tr)) != 3 || tr.Len() != 3 {
+		t.Error("range should be reusable")
+	}
+
+	if !tr.Contains(parseTime("2019-09-09")) || !tr.Contains(parseTime("2019-09-29")) || tr.Contains(parseTime("2019-09-30")) {
+		t.Error("unexpected contains")
+	}
+
+	days := tr.Split(Daily)
+	if days.Len() != 21 || !days.Start.Equal(parseTime("2019-09-09")) || !days.End.Equal(parseTime("2019-09-29")) {
+		t.Error("unexpected split", days.Start, days.End)
+	}
+
+	for _, f := range []Frequency{Daily, Weekly, Monthly, FiscalMonthly, Yearly} {
+		r := NewTimeRange(parseTime("2019-12-30"), parseTime("2024-03-01"), f)
+		n := 0
+		for range r.Periods() {
+			n++
+		}
+		if r.Len() != n {
+			t.Error("unexpected len", f, r.Len(), n)
+		}
+	}
+	if n := NewTimeRange(parseTime("2019-09-10"), parseTime("2019-09-09"), Daily).Len(); n != 0 {
+		t.Error("expected an empty range", n)
+	}
+	// counted, not iterated
+	if n := NewTimeRange(parseTime("0001-01-01"), parseTime("9999-12-31"), Daily).Len(); n != 3652059 {
+		t.Error("unexpected len of every day", n)
+	}
+
+	fiscal := NewPeriod(parseTime("2024-01"), FiscalMonthly)
+	if !fiscal.Start.Equal(parseTime("2023-12-31")) || fiscal.Days() != 35 {
+		t.Errorf("unexpected fiscal period %+v", fiscal)
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	periods := Decompose(parseTime("2022-01-01"), parseTime("2024-06-15"))
+
+	count := map[Frequency]int{}
+	days := 0
+	next := parseTime("2022-01-01")
+	for _, p := range periods {
+		if !p.Start.Equal(next) {
+			t.Fatalf("gap or overlap at %+v", p)
+		}
+		next = p.End.AddDate(0, 0, 1)
+		count[p.Frequency]++
+		days += p.Days()
+	}
+
+	// 2022 and 2023, january through may, june 1st and 2nd, the week of the 3rd, then the 10th to 15th
+	expected := map[Frequency]int{Yearly: 2, Monthly: 5, Weekly: 1, Daily: 8}
+	if !reflect.DeepEqual(expected, count) || days != 897 {
+		t.Error("unexpected decomposition", count, days)
+	}
+
+	periods = NewTimeRange(parseTime("2024-01-01"), parseTime("2024-02-29"), Daily).Decompose(Monthly)
+	if len(periods) != 2 || periods[1].Frequency != Monthly {
+		t.Error("expected two months", periods)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tr := NewTimeRange(parseTime("2022-01-01"), parseTime("2024-06-15"), Daily)
+	at := func(s string) func() time.Time {
+		return func() time.Time { return parseTime(s).Add(20 * time.Hour) }
+	}
+
+	count := func(periods []Period) map[Frequency]int {
+		c := map[Frequency]int{}
+		for _, p := range periods {
+			c[p.Frequency]++
+		}
+		return c
+	}
+
+	plan := Plan(tr, ReportSales, PlanOptions{Now: at("2024-07-01")})
+	if c := count(plan); c[Yearly] != 2 || c[Monthly] != 5 || len(plan) != 16 {
+		t.Error("unexpected sales plan", c)
+	}
+
+	// may is not published until june 5th, so april through june 2nd are nine weeks instead
+	plan = Plan(tr, ReportSales, PlanOptions{Now: at("2024-06-04")})
+	if c := count(plan); c[Monthly] != 3 || c[Weekly] != 9 || c[Daily] != 13 {
+		t.Error("unexpected plan before may is published", c)
+	}
+
+	if plan := Plan(tr, ReportSubscription, PlanOptions{Now: at("2024-07-01")}); len(plan) != 897 {
+		t.Error("subscription reports are daily only", len(plan))
+	}
+
+	if plan := Plan(tr, ReportSales, PlanOptions{KeepDaily: true}); len(plan) != 897 {
+		t.Error("expected daily plan", len(plan))
+	}
+}
@@ -0,0 +1,158 @@
+package appstoreconnect
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relative and named time range expressions, resolved against a clock in Apple's reporting time
+// zone, for jobs which run on a schedule rather than for fixed dates
+
+var lastN = regexp.MustCompile(`^last-(\d+)([dwmy])$`)
+
+// maxLastN bounds the N of last-N, which covers a few centuries of daily reports already
+const maxLastN = 100000
+
+// RangeParser parses time ranges which may be relative to now, counting days in
+// ReportingLocation like Apple's reports. The zero value is ready to use.
+//
+// In addition to the absolute dates accepted by ParseTimeRange either side of a range may be
+//
+//	today, yesterday
+//	mtd, ytd              month and year to date, daily, through yesterday. On the first day
+//	                      of a month or year, which has no complete day yet, the whole
+//	                      previous month or year
+//	last-week, last-month, last-year
+//	last-fiscal-period    the previous Apple fiscal period, for finance reports
+//	last-7d, last-4w, last-3m, last-1y, with N up to 100000
+//
+// and the end of a range may be left open, as in 2024-01:, to run through the latest period
+// Apple has published.
+type RangeParser struct {
+	// Now is the clock, time.Now if nil
+	Now func() time.Time
+
+	// ReportType decides the latest published period of open ranges, SALES if empty
+	ReportType ReportType
+
+	// Calendar is used for fiscal expressions, AppleFiscalCalendar if nil
+	Calendar *FiscalCalendar
+}
+
+// Parse parses a single date, expression or colon separated range of them
+func (p *RangeParser) Parse(value string) (*TimeRange, error) {
+	if value == "" {
+		return nil, ErrTimeFormatInvalid
+	}
+
+	first, second, isRange := strings.Cut(value, ":")
+	start, end, f, err := p.span(first)
+	if err != nil {
+		return nil, err
+	}
+
+	if isRange {
+		if second == "" {
+			end = LatestAvailable(p.reportType(), f, p.now())
+			return NewTimeRange(start, end, f), nil
+		}
+
+		s2, e2, f2, err := p.span(second)
+		if err != nil {
+			return nil, err
+		}
+		// ranges may be written backwards
+		if s2.Before(start) {
+			start = s2
+		} else {
+			end = periodEnd(e2, f2)
+		}
+	}
+
+	return NewTimeRange(start, end, f), nil
+}
+
+// span resolves one side of a range to the first and last period it covers
+func (p *RangeParser) span(expr string) (time.Time, time.Time, Frequency, error) {
+	today := p.today()
+	yesterday := today.AddDate(0, 0, -1)
+
+	switch expr {
+	case "today":
+		return today, today, Daily, nil
+	case "yesterday":
+		return yesterday, yesterday, Daily, nil
+	case "mtd":
+		return roundDown(yesterday, Monthly), yesterday, Daily, nil
+	case "ytd":
+		return roundDown(yesterday, Yearly), yesterday, Daily, nil
+	case "last-week":
+		t := weekEnding(today).AddDate(0, 0, -7)
+		return t, t, Weekly, nil
+	case "last-month":
+		t := roundDown(today, Monthly).AddDate(0, -1, 0)
+		return t, t, Monthly, nil
+	case "last-year":
+		t := roundDown(today, Yearly).AddDate(-1, 0, 0)
+		return t, t, Yearly, nil
+	case "last-fiscal-period":
+		t := p.calendar().PeriodOf(today).Month().AddDate(0, -1, 0)
+		return t, t, FiscalMonthly, nil
+	}
+
+	if m := lastN.FindStringSubmatch(expr); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > maxLastN {
+			return time.Time{}, time.Time{}, Daily, ErrTimeFormatInvalid
+		}
+
+		var f Frequency
+		var start, end time.Time
+		switch m[2] {
+		case "d":
+			f, end = Daily, yesterday
+			start = end.AddDate(0, 0, 1-n)
+		case "w":
+			f, end = Weekly, weekEnding(today).AddDate(0, 0, -7)
+			start = end.AddDate(0, 0, -7*(n-1))
+		case "m":
+			f, end = Monthly, roundDown(today, Monthly).AddDate(0, -1, 0)
+			start = end.AddDate(0, 1-n, 0)
+		case "y":
+			f, end = Yearly, roundDown(today, Yearly).AddDate(-1, 0, 0)
+			start = end.AddDate(1-n, 0, 0)
+		}
+		return start, end, f, nil
+	}
+
+	t, f, err := parseTimeAndFrequency(expr)
+	return t, t, f, err
+}
+
+// today is the current reporting day, as a date at midnight UTC like every other report date
+func (p *RangeParser) today() time.Time {
+	return ReportingDay(p.now())
+}
+
+func (p *RangeParser) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+func (p *RangeParser) reportType() ReportType {
+	if p.ReportType == "" {
+		return ReportSales
+	}
+	return p.ReportType
+}
+
+func (p *RangeParser) calendar() FiscalCalendar {
+	if p.Calendar == nil {
+		return AppleFiscalCalendar
+	}
+	return *p.Calendar
+}
@@ -0,0 +1,171 @@
+package appstoreconnect
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// The api authenticates with short lived ES256 JWTs. They are signed through a TokenSigner so the
+// key can be kept in a KMS or HSM instead of in memory, and minted again shortly before they expire.
+
+// TokenLifetime is how long minted tokens are valid. Apple rejects tokens valid for over 20 minutes,
+// the minute short of that allows for clocks which differ from apple's.
+const TokenLifetime = 19 * time.Minute
+
+// tokenRefresh is how long before expiry a token is replaced, so it does not expire in flight
+const tokenRefresh = time.Minute
+
+// KeyType is the kind of api key a token is signed with
+type KeyType string
+
+const (
+	// KeyTypeTeam keys belong to the team and identify it with the issuer id, the default
+	KeyTypeTeam KeyType = "team"
+	// KeyTypeIndividual keys belong to a user and have no issuer id
+	KeyTypeIndividual KeyType = "individual"
+)
+
+var (
+	// ErrUnknownKeyType the key type is neither team nor individual
+	ErrUnknownKeyType = errors.New("token: key type must be team or individual")
+
+	// ErrSignerNotP256 the signer does not hold the P-256 key ES256 requires
+	ErrSignerNotP256 = errors.New("token: signer must hold an ECDSA P-256 key")
+
+	// ErrBadSignature the signer returned something other than an ASN.1 ECDSA signature
+	ErrBadSignature = errors.New("token: signer returned an invalid ECDSA signature")
+)
+
+// TokenSigner signs tokens with the ES256 key of an api key. It is a crypto.Signer over a P-256
+// key returning ASN.1 DER signatures of SHA-256 digests, as *ecdsa.PrivateKey and the
+// asymmetric signing APIs of cloud KMSs do.
+type TokenSigner interface {
+	crypto.Signer
+}
+
+// NewP8Signer parses a .p8 private key, as downloaded from App Store Connect, into an in memory
+// TokenSigner. This is what NewClient uses unless WithSigner is given.
+func NewP8Signer(p8 []byte) (TokenSigner, error) {
+	return parseP8PrivKey(decodeKey(p8))
+}
+
+// WithSigner signs tokens with signer instead of the private key in the credentials, which may
+// then be left empty
+func WithSigner(signer TokenSigner) ClientOption {
+	return func(c *Client) {
+		c.tokens.signer = signer
+	}
+}
+
+// Token returns the bearer token requests are currently made with
+func (c *Client) Token() (string, error) {
+	return c.tokens.Token()
+}
+
+// tokenSource mints and caches tokens, it is shared by the clients of every vendor of a key
+type tokenSource struct {
+	signer   TokenSigner
+	keyID    string
+	issuerID string
+	keyType  KeyType
+	scope    []string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a token valid for at least another minute
+func (s *tokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Add(tokenRefresh).Before(s.expires) {
+		return s.token, nil
+	}
+
+	expires := now.Add(TokenLifetime)
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{"appstoreconnect-v1"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		Scope: s.scope,
+	}
+	if s.keyType == KeyTypeIndividual {
+		claims.Subject = "user"
+	} else {
+		claims.Issuer = s.issuerID
+	}
+	token := jwt.NewWithClaims(signingMethodSigner, claims)
+	token.Header["kid"] = s.keyID
+
+	signed, err := token.SignedString(s.signer)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expires = signed, expires
+	return s.token, nil
+}
+
+// tokenClaims are the claims of both team and individual key tokens
+type tokenClaims struct {
+	jwt.RegisteredClaims
+
+	// Scope restricts the token to requests like "GET /v1/salesReports?filter[vendorNumber]=123"
+	Scope []string `json:"scope,omitempty"`
+}
+
+// signingMethodSigner is ES256 over a crypto.Signer rather than an *ecdsa.PrivateKey
+var signingMethodSigner = &signerMethod{}
+
+type signerMethod struct{}
+
+func (m *signerMethod) Alg() string {
+	return jwt.SigningMethodES256.Alg()
+}
+
+func (m *signerMethod) Verify(signingString string, sig []byte, key any) error {
+	return jwt.SigningMethodES256.Verify(signingString, sig, key)
+}
+
+// Sign converts the ASN.1 signature of the signer into the fixed size r || s of a JWS
+func (m *signerMethod) Sign(signingString string, key any) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P256() {
+		return nil, ErrSignerNotP256
+	}
+
+	digest := sha256.Sum256([]byte(signingString))
+	der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) > 0 {
+		return nil, ErrBadSignature
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.BitLen() > 256 || sig.S.BitLen() > 256 {
+		return nil, ErrBadSignature
+	}
+	out := make([]byte, 64)
+	sig.R.FillBytes(out[:32])
+	sig.S.FillBytes(out[32:])
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package appstoreconnect_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+)
+
+func TestTokenSigner(t *testing.T) {
+	signer := appstoreconnecttest.NewSigner()
+	creds := appstoreconnect.NewCredentials("KEY", "ISSUER", "")
+	client, err := appstoreconnect.NewClient(creds, appstoreconnect.WithSigner(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.RegisteredClaims{}
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return signer.Public(), nil
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience("appstoreconnect-v1"), jwt.WithIssuer("ISSUER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != "KEY" {
+		t.Error("unexpected kid", parsed.Header["kid"])
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > appstoreconnect.TokenLifetime || ttl < appstoreconnect.TokenLifetime-time.Minute {
+		t.Error("unexpected expiry", claims.ExpiresAt)
+	}
+
+	again, _ := client.Token()
+	if again != token || signer.Signed() != 1 {
+		t.Error("expected the token to be reused", signer.Signed())
+	}
+	if other, _ := client.WithVendor("200").Token(); other != token {
+		t.Error("expected vendors to share the token")
+	}
+}
+
+func TestTokenSignerFailure(t *testing.T) {
+	signer := appstoreconnecttest.NewSigner()
+	signer.Err = errors.New("kms unavailable")
+	_, err := appstoreconnect.NewClient(&appstoreconnect.Credentials{}, appstoreconnect.WithSigner(signer))
+	if !errors.Is(err, signer.Err) {
+		t.Error("expected the signer error", err)
+	}
+}
+
+func TestP8Signer(t *testing.T) {
+	p8 := appstoreconnecttest.NewSigner().P8()
+	if _, err := appstoreconnect.NewP8Signer(p8); err != nil {
+		t.Error(err)
+	}
+	if _, err := appstoreconnect.NewClient(appstoreconnect.NewCredentials("KEY", "ISSUER", string(p8))); err != nil {
+		t.Error(err)
+	}
+	if _, err := appstoreconnect.NewClient(appstoreconnect.NewCredentials("KEY", "ISSUER", "")); err != appstoreconnect.ErrAuthKeyNotPem {
+		t.Error("expected not pem", err)
+	}
+}
+
+func TestIndividualKeyToken(t *testing.T) {
+	signer := appstoreconnecttest.NewSigner()
+	creds := &appstoreconnect.Credentials{
+		KeyID:   "KEY",
+		KeyType: appstoreconnect.KeyTypeIndividual,
+		Scope:   []string{"GET /v1/salesReports"},
+	}
+	client, err := appstoreconnect.NewClient(creds, appstoreconnect.WithSigner(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return signer.Public(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "user" {
+		t.Error("expected user subject", claims["sub"])
+	}
+	if _, ok := claims["iss"]; ok {
+		t.Error("expected no issuer", claims["iss"])
+	}
+	if scope, ok := claims["scope"].([]any); !ok || len(scope) != 1 || scope[0] != "GET /v1/salesReports" {
+		t.Error("unexpected scope", claims["scope"])
+	}
+
+	creds.KeyType = "admin"
+	if _, err := appstoreconnect.NewClient(creds, appstoreconnect.WithSigner(signer)); err != appstoreconnect.ErrUnknownKeyType {
+		t.Error("expected unknown key type", err)
+	}
+}
+
+func TestServerRejectsLongLivedTokens(t *testing.T) {
+	server := appstoreconnecttest.NewServer()
+	defer server.Close()
+
+	request := func(issued time.Time, expires time.Time) int {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+			Issuer:    appstoreconnecttest.IssuerID,
+			Audience:  jwt.ClaimStrings{"appstoreconnect-v1"},
+			IssuedAt:  jwt.NewNumericDate(issued),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		})
+		token.Header["kid"] = appstoreconnecttest.KeyID
+		signed, err := token.SignedString(server.Signer.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/salesReports", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// expiring within 20 minutes from now is not enough, apple counts from iat
+	now := time.Now()
+	if status := request(now.Add(-2*time.Minute), now.Add(19*time.Minute)); status != http.StatusUnauthorized {
+		t.Error("expected a token valid for 21 minutes to be rejected", status)
+	}
+	if status := request(now, now.Add(appstoreconnect.TokenLifetime)); status != http.StatusNotFound {
+		t.Error("expected a token valid for TokenLifetime to be accepted", status)
+	}
+}
@@ -0,0 +1,324 @@
+package appstoreconnecttest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// Synthetic reports for load tests and demos. Every period is generated from the seed and the
+// period alone, so the same period always has the same rows whatever range it is generated in.
+
+// Product is an app or in-app purchase sold in generated reports
+type Product struct {
+	SKU     string
+	Title   string
+	AppleID string
+	// Type is the product type identifier, like 1F for a paid app or IAY for a subscription
+	Type string
+	// Parent is the SKU of the app an in-app purchase belongs to
+	Parent string
+	// Price is in USD, converted to each country's currency
+	Price float64
+	// Popularity is the mean units sold per day in a country
+	Popularity float64
+}
+
+// Country is a storefront in generated reports
+type Country struct {
+	Code     string
+	Currency string
+	// Rate is units of Currency per USD
+	Rate float64
+	// Region is the finance report region the country is paid in
+	Region string
+}
+
+// DefaultProducts are sold by a NewGenerator
+var DefaultProducts = []Product{
+	{SKU: "com.example.weather", Title: "Weather Pro", AppleID: "1450000001", Type: "1F", Price: 2.99, Popularity: 40},
+	{SKU: "com.example.weather.premium", Title: "Weather Pro Premium", AppleID: "1450000002", Type: "IAY", Parent: "com.example.weather", Price: 9.99, Popularity: 12},
+	{SKU: "com.example.weather.themes", Title: "Theme Pack", AppleID: "1450000003", Type: "IA1", Parent: "com.example.weather", Price: 0.99, Popularity: 8},
+	{SKU: "com.example.notes", Title: "Field Notes", AppleID: "1450000004", Type: "1F", Price: 4.99, Popularity: 15},
+	{SKU: "com.example.notes.sync", Title: "Field Notes Sync", AppleID: "1450000005", Type: "IAY", Parent: "com.example.notes", Price: 1.99, Popularity: 6},
+}
+
+// DefaultCountries are the storefronts of a NewGenerator
+var DefaultCountries = []Country{
+	{Code: "US", Currency: "USD", Rate: 1, Region: "US"},
+	{Code: "CA", Currency: "CAD", Rate: 1.35, Region: "CA"},
+	{Code: "GB", Currency: "GBP", Rate: 0.79, Region: "GB"},
+	{Code: "DE", Currency: "EUR", Rate: 0.92, Region: "EU"},
+	{Code: "FR", Currency: "EUR", Rate: 0.92, Region: "EU"},
+	{Code: "JP", Currency: "JPY", Rate: 150, Region: "JP"},
+	{Code: "AU", Currency: "AUD", Rate: 1.52, Region: "AU"},
+	{Code: "BR", Currency: "BRL", Rate: 5.1, Region: "WW"},
+}
+
+var devices = []string{"iPhone", "iPad", "Desktop"}
+
+// Generator produces plausible sales and finance reports
+type Generator struct {
+	Seed      uint64
+	Products  []Product
+	Countries []Country
+
+	// Developer fills the developer and artist columns
+	Developer string
+
+	// RefundRate is the fraction of units refunded, PromoRate the fraction sold with promo codes
+	RefundRate float64
+	PromoRate  float64
+}
+
+// NewGenerator creates a generator of the default products and countries
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{
+		Seed:       seed,
+		Products:   DefaultProducts,
+		Countries:  DefaultCountries,
+		Developer:  "Example Software LLC",
+		RefundRate: 0.02,
+		PromoRate:  0.03,
+	}
+}
+
+// Sales generates a summary sales report for every period of tr
+func (g *Generator) Sales(tr *appstoreconnect.TimeRange) *appstoreconnect.SalesReportResponse {
+	ret := &appstoreconnect.SalesReportResponse{}
+	for p := range tr.Periods() {
+		ret.Reports = append(ret.Reports, g.SalesPeriod(p)...)
+	}
+	return ret
+}
+
+// SalesPeriod generates the summary sales report of a single period
+func (g *Generator) SalesPeriod(p appstoreconnect.Period) []*appstoreconnect.SalesReportItem {
+	r := g.rand("sales", p)
+	items := []*appstoreconnect.SalesReportItem{}
+	for _, prod := range g.Products {
+		for _, c := range g.Countries {
+			device := devices[r.IntN(len(devices))]
+			units := g.units(r, prod, p)
+			refunds := binomial(r, units, g.RefundRate)
+			promos := binomial(r, units-refunds, g.PromoRate)
+
+			add := func(units int, price float64, promo string) {
+				if units == 0 {
+					return
+				}
+				items = append(items, g.salesItem(prod, c, p, device, units, price, promo))
+			}
+			add(units-refunds-promos, prod.Price, "")
+			add(-refunds, prod.Price, "")
+			add(promos, 0, promoCode(r, p))
+		}
+	}
+	return items
+}
+
+// Finance generates a finance report for every fiscal period covering tr, for the countries
+// paid in regionCode, or every country for "ZZ"
+func (g *Generator) Finance(tr *appstoreconnect.TimeRange, regionCode string) *appstoreconnect.FinanceReportResponse {
+	ret := &appstoreconnect.FinanceReportResponse{}
+	for p := range tr.Fiscal(appstoreconnect.AppleFiscalCalendar).Periods() {
+		ret.Reports = append(ret.Reports, g.FinancePeriod(p, regionCode)...)
+	}
+	return ret
+}
+
+// FinancePeriod generates the finance report of a single fiscal period
+func (g *Generator) FinancePeriod(p appstoreconnect.Period, regionCode string) []*appstoreconnect.FinanceReportItem {
+	r := g.rand("finance/"+regionCode, p)
+	items := []*appstoreconnect.FinanceReportItem{}
+	for _, prod := range g.Products {
+		for _, c := range g.Countries {
+			if regionCode != "ZZ" && c.Region != regionCode {
+				continue
+			}
+			units := g.units(r, prod, p)
+			refunds := binomial(r, units, g.RefundRate)
+
+			add := func(quantity int, salesOrReturn string) {
+				if quantity == 0 {
+					return
+				}
+				items = append(items, g.financeItem(prod, c, p, quantity, salesOrReturn))
+			}
+			add(units-refunds, "S")
+			add(-refunds, "R")
+		}
+	}
+	return items
+}
+
+// SalesTSV is a summary sales report for one period, as apple serves it
+func (g *Generator) SalesTSV(p appstoreconnect.Period) string {
+	rows := [][]string{}
+	for _, item := range g.SalesPeriod(p) {
+		rows = append(rows, item.Values())
+	}
+	return writeTSV((&appstoreconnect.SalesReportItem{}).GetHeader(), rows, nil)
+}
+
+// FinanceTSV is a finance report for one fiscal period, as apple serves it, with the total rows
+func (g *Generator) FinanceTSV(p appstoreconnect.Period, regionCode string) string {
+	items := g.FinancePeriod(p, regionCode)
+	amount, units := 0.0, 0
+	rows := [][]string{}
+	for _, item := range items {
+		q, _ := strconv.Atoi(item.Quantity)
+		share, _ := strconv.ParseFloat(item.ExtendedPartnerShare, 64)
+		amount += share
+		units += q
+		rows = append(rows, item.Values())
+	}
+	footer := [][]string{
+		{"Total_Rows", fmt.Sprint(len(items))},
+		{"Total_Amount", money(amount)},
+		{"Total_Units", fmt.Sprint(units)},
+	}
+	return writeTSV((&appstoreconnect.FinanceReportItem{}).GetHeader(), rows, footer)
+}
+
+// AddGeneratedSales serves generated summary sales reports for every period of tr
+func (s *Server) AddGeneratedSales(g *Generator, tr *appstoreconnect.TimeRange) {
+	for p := range tr.Periods() {
+		s.AddSalesReport(p.Frequency, reportDate(p), appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, g.SalesTSV(p))
+	}
+}
+
+// AddGeneratedFinance serves generated finance reports for every fiscal period covering tr
+func (s *Server) AddGeneratedFinance(g *Generator, tr *appstoreconnect.TimeRange, regionCode string) {
+	for p := range tr.Fiscal(appstoreconnect.AppleFiscalCalendar).Periods() {
+		s.AddFinanceReport(p.Date.Format("2006-01"), regionCode, g.FinanceTSV(p, regionCode))
+	}
+}
+
+func (g *Generator) salesItem(prod Product, c Country, p appstoreconnect.Period, device string, units int, usd float64, promo string) *appstoreconnect.SalesReportItem {
+	price := usd * c.Rate
+	item := &appstoreconnect.SalesReportItem{
+		Provider:              "APPLE",
+		ProviderCountry:       "US",
+		SKU:                   prod.SKU,
+		Developer:             g.Developer,
+		Title:                 prod.Title,
+		Version:               "1.0",
+		ProductTypeIdentifier: prod.Type,
+		Units:                 units,
+		DeveloperProceeds:     money(price * proceedsShare(prod)),
+		BeginDate:             p.Start.Format("01/02/2006"),
+		EndDate:               p.End.Format("01/02/2006"),
+		CustomerCurrency:      c.Currency,
+		CountryCode:           c.Code,
+		CurrencyOfProceeds:    c.Currency,
+		AppleIdentifier:       prod.AppleID,
+		CustomerPrice:         money(price),
+		PromoCode:             promo,
+		ParentIdentifier:      prod.Parent,
+		Device:                device,
+	}
+	if prod.Type == "IAY" {
+		item.Subscription = "Renewal"
+		item.Period = "1 Year"
+		item.ProceedsReason = "Rate After One Year"
+	}
+	return item
+}
+
+func (g *Generator) financeItem(prod Product, c Country, p appstoreconnect.Period, quantity int, salesOrReturn string) *appstoreconnect.FinanceReportItem {
+	price := prod.Price * c.Rate
+	share := price * proceedsShare(prod)
+	return &appstoreconnect.FinanceReportItem{
+		StartDate:                 p.Start.Format("01/02/2006"),
+		EndDate:                   p.End.Format("01/02/2006"),
+		VendorIdentifier:          prod.SKU,
+		Quantity:                  fmt.Sprint(quantity),
+		PartnerShare:              money(share),
+		ExtendedPartnerShare:      money(share * float64(quantity)),
+		PartnerShareCurrency:      c.Currency,
+		SalesOrReturn:             salesOrReturn,
+		AppleIdentifier:           prod.AppleID,
+		ArtistShowDeveloperAuthor: g.Developer,
+		Title:                     prod.Title,
+		ProductTypeIdentifier:     prod.Type,
+		CountryOfSale:             c.Code,
+		CustomerPrice:             money(price),
+		CustomerCurrency:          c.Currency,
+	}
+}
+
+// rand is seeded by the generator seed, the kind of report and the period
+func (g *Generator) rand(kind string, p appstoreconnect.Period) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprint(h, kind, p.Frequency, p.Date.Format(time.DateOnly))
+	return rand.New(rand.NewPCG(g.Seed, h.Sum64()))
+}
+
+// units sold of prod in a country over p, around its popularity with more on weekends
+func (g *Generator) units(r *rand.Rand, prod Product, p appstoreconnect.Period) int {
+	mean := 0.0
+	for d := p.Start; !d.After(p.End); d = d.AddDate(0, 0, 1) {
+		weight := 1.0
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			weight = 1.3
+		}
+		mean += prod.Popularity * weight / float64(len(g.Countries))
+	}
+	n := int(math.Round(mean * (0.7 + 0.6*r.Float64())))
+	return max(n, 0)
+}
+
+// proceedsShare is what the developer keeps, more for subscriptions after their first year
+func proceedsShare(prod Product) float64 {
+	if prod.Type == "IAY" {
+		return 0.85
+	}
+	return 0.7
+}
+
+func binomial(r *rand.Rand, n int, p float64) int {
+	if n <= 0 || p <= 0 {
+		return 0
+	}
+	mean := float64(n) * p
+	k := int(math.Round(mean + r.NormFloat64()*math.Sqrt(mean*(1-p))))
+	return min(max(k, 0), n)
+}
+
+func promoCode(r *rand.Rand, p appstoreconnect.Period) string {
+	return fmt.Sprintf("PROMO%d%02d", p.Start.Year()%100, r.IntN(20))
+}
+
+func money(f float64) string {
+	return fmt.Sprintf("%.2f", f)
+}
+
+// reportDate is the reportDate filter requesting p
+func reportDate(p appstoreconnect.Period) string {
+	switch p.Frequency {
+	case appstoreconnect.Yearly:
+		return p.Date.Format("2006")
+	case appstoreconnect.Monthly, appstoreconnect.FiscalMonthly:
+		return p.Date.Format("2006-01")
+	}
+	return p.Date.Format(time.DateOnly)
+}
+
+// writeTSV writes a report with its footer rows
+func writeTSV(header []string, rows [][]string, footer [][]string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Comma = '\t'
+	w.Write(header)
+	w.WriteAll(rows)
+	w.WriteAll(footer)
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package appstoreconnecttest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// Credentials the S3Server accepts
+const (
+	S3AccessKeyID     = "TESTACCESSKEY"
+	S3SecretAccessKey = "testsecret"
+)
+
+// S3Server is an in memory stand in for an S3 compatible object store, supporting path style
+// PUT, GET and HEAD of objects. Requests must be signed with S3AccessKeyID and carry the digest
+// of their body, as S3 requires.
+type S3Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+}
+
+// NewS3Server starts a server, stop it with Close
+func NewS3Server() *S3Server {
+	s := &S3Server{objects: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Sink creates a sink writing to bucket below prefix on the server
+func (s *S3Server) Sink(bucket string, prefix string) *appstoreconnect.S3Sink {
+	return &appstoreconnect.S3Sink{
+		Endpoint:        s.URL,
+		Region:          "us-east-1",
+		Bucket:          bucket,
+		Prefix:          prefix,
+		AccessKeyID:     S3AccessKeyID,
+		SecretAccessKey: S3SecretAccessKey,
+	}
+}
+
+// Objects returns the stored objects by bucket/key
+func (s *S3Server) Objects() map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.objects)
+}
+
+// Puts is the number of objects written
+func (s *S3Server) Puts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.puts
+}
+
+func (s *S3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+S3AccessKeyID+"/") || !strings.Contains(auth, "Signature=") {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+		s.puts++
+	case http.MethodGet, http.MethodHead:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(obj)
+		}
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
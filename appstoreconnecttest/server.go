@@ -0,0 +1,248 @@
+package appstoreconnecttest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// Identifiers of the api key the Server accepts tokens for
+const (
+	KeyID        = "TESTKEY123"
+	IssuerID     = "00000000-0000-0000-0000-000000000000"
+	VendorNumber = "80000000"
+)
+
+// Filters are the filter[...] query parameters of a request, by name without the filter[]
+type Filters map[string]string
+
+// Request is a request the Server received
+type Request struct {
+	Method  string
+	Path    string
+	Filters Filters
+	Header  http.Header
+	// Status is the status the Server responded with
+	Status int
+}
+
+// Server is a fake App Store Connect api. It checks tokens like apple does, serves reports added
+// with AddReport, gzipped, and responds 404 to requests for anything else.
+type Server struct {
+	*httptest.Server
+
+	// Signer holds the key tokens must be signed with
+	Signer *Signer
+
+	// RateLimit is the hourly request limit reported in the X-Rate-Limit header
+	RateLimit int
+
+	mu       sync.Mutex
+	reports  map[string][]byte
+	failures []int
+	requests []Request
+}
+
+// NewServer starts a server, stop it with Close
+func NewServer() *Server {
+	s := &Server{
+		Signer:    NewSigner(),
+		RateLimit: 3600,
+		reports:   map[string][]byte{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Credentials are valid credentials for the server
+func (s *Server) Credentials() *appstoreconnect.Credentials {
+	return &appstoreconnect.Credentials{
+		KeyID:        KeyID,
+		IssuerID:     IssuerID,
+		PrivKey:      string(s.Signer.P8()),
+		VendorNumber: VendorNumber,
+	}
+}
+
+// Client creates a client talking to the server
+func (s *Server) Client(opts ...appstoreconnect.ClientOption) (*appstoreconnect.Client, error) {
+	opts = append([]appstoreconnect.ClientOption{appstoreconnect.WithBaseURL(s.URL + "/v1/")}, opts...)
+	return appstoreconnect.NewClient(s.Credentials(), opts...)
+}
+
+// AddReport serves tsv for requests to path, like "salesReports", with exactly these filters
+func (s *Server) AddReport(path string, filters Filters, tsv string) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(tsv))
+	w.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[reportKey(path, filters)] = gz.Bytes()
+}
+
+// AddSalesReport serves a sales report for the server's vendor
+func (s *Server) AddSalesReport(frequency appstoreconnect.Frequency, reportDate string, reportType appstoreconnect.ReportType, reportSubType appstoreconnect.ReportSubType, tsv string) {
+	s.AddReport("salesReports", Filters{
+		"vendorNumber":  VendorNumber,
+		"frequency":     string(frequency),
+		"reportDate":    reportDate,
+		"reportType":    string(reportType),
+		"reportSubType": string(reportSubType),
+	}, tsv)
+}
+
+// AddFinanceReport serves a finance report for the server's vendor, reportDate is like "2024-05"
+func (s *Server) AddFinanceReport(reportDate string, regionCode string, tsv string) {
+	s.AddReport("financeReports", Filters{
+		"vendorNumber": VendorNumber,
+		"reportDate":   reportDate,
+		"regionCode":   regionCode,
+		"reportType":   string(appstoreconnect.ReportFinancial),
+	}, tsv)
+}
+
+// Fail responds to the next times requests with status, such as 401, 429 or 503, before any
+// other checks
+func (s *Server) Fail(status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range times {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// Requests lists the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := Request{
+		Method:  r.Method,
+		Path:    strings.TrimPrefix(r.URL.Path, "/v1/"),
+		Filters: Filters{},
+		Header:  r.Header.Clone(),
+	}
+	for k, v := range r.URL.Query() {
+		if name, ok := strings.CutPrefix(k, "filter["); ok && len(v) > 0 {
+			req.Filters[strings.TrimSuffix(name, "]")] = v[0]
+		}
+	}
+
+	req.Status = s.respond(w, r, req)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+}
+
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, req Request) int {
+	s.mu.Lock()
+	var failure int
+	if len(s.failures) > 0 {
+		failure, s.failures = s.failures[0], s.failures[1:]
+	}
+	report, ok := s.reports[reportKey(req.Path, req.Filters)]
+	remaining := max(s.RateLimit-len(s.requests)-1, 0)
+	s.mu.Unlock()
+
+	w.Header().Set("X-Rate-Limit", fmt.Sprintf("user-hour-lim:%d;user-hour-rem:%d;", s.RateLimit, remaining))
+
+	if failure != 0 {
+		if failure == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		return writeError(w, failure, http.StatusText(failure))
+	}
+	if err := s.checkToken(r.Header.Get("Authorization")); err != nil {
+		return writeError(w, http.StatusUnauthorized, err.Error())
+	}
+	if r.Method != http.MethodGet {
+		return writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+	}
+	if !ok {
+		return writeError(w, http.StatusNotFound, "The specified resource does not exist")
+	}
+
+	w.Header().Set("Content-Type", "application/a-gzip")
+	w.Header().Set("Content-Length", strconv.Itoa(len(report)))
+	w.Write(report)
+	return http.StatusOK
+}
+
+// maxTokenLifetime is the longest apple accepts between a token's iat and exp
+const maxTokenLifetime = 20 * time.Minute
+
+// checkToken validates the bearer token the way apple does: signed by the key, for the api
+// audience, with a kid and an expiry no more than 20 minutes after it was issued
+func (s *Server) checkToken(authorization string) error {
+	bearer, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return errors.New("missing bearer token")
+	}
+
+	claims := jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(bearer, &claims, func(*jwt.Token) (any, error) {
+		return s.Signer.Public(), nil
+	},
+		jwt.WithValidMethods([]string{"ES256"}),
+		jwt.WithAudience("appstoreconnect-v1"),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if token.Header["kid"] != KeyID {
+		return fmt.Errorf("unknown kid %v", token.Header["kid"])
+	}
+	if claims.IssuedAt == nil {
+		return errors.New("token has no issued at time")
+	}
+	if claims.ExpiresAt.Sub(claims.IssuedAt.Time) > maxTokenLifetime {
+		return errors.New("token is valid for more than 20 minutes")
+	}
+	if claims.Issuer != IssuerID && claims.Subject != "user" {
+		return errors.New("token has neither the issuer id nor a user subject")
+	}
+	return nil
+}
+
+// writeError responds with an error document shaped like apple's
+func writeError(w http.ResponseWriter, status int, detail string) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{{
+			"status": strconv.Itoa(status),
+			"code":   strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_")),
+			"title":  http.StatusText(status),
+			"detail": detail,
+		}},
+	})
+	return status
+}
+
+func reportKey(path string, filters Filters) string {
+	parts := []string{}
+	for _, k := range slices.Sorted(maps.Keys(filters)) {
+		parts = append(parts, k+"="+filters[k])
+	}
+	return path + "?" + strings.Join(parts, "&")
+}
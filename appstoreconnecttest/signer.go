@@ -0,0 +1,66 @@
+// Package appstoreconnecttest provides test doubles for code using the appstoreconnect package
+package appstoreconnecttest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"sync"
+)
+
+// Signer is an appstoreconnect.TokenSigner holding a freshly generated key, standing in for a
+// key held in a KMS or HSM
+type Signer struct {
+	Key *ecdsa.PrivateKey
+
+	// Err is returned by Sign instead of a signature when set
+	Err error
+
+	mu     sync.Mutex
+	signed int
+}
+
+// NewSigner creates a signer with a new P-256 key
+func NewSigner() *Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic("appstoreconnecttest: generate key: " + err.Error())
+	}
+	return &Signer{Key: key}
+}
+
+// Public is the public key tokens are verified with
+func (s *Signer) Public() crypto.PublicKey {
+	return s.Key.Public()
+}
+
+// Sign signs digest with the key, counting the signatures made
+func (s *Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.signed++
+	return s.Key.Sign(rand, digest, opts)
+}
+
+// Signed is the number of signatures made
+func (s *Signer) Signed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.signed
+}
+
+// P8 is the key as a .p8 file, for credentials which need a private key
+func (s *Signer) P8() []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(s.Key)
+	if err != nil {
+		panic("appstoreconnecttest: marshal key: " + err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/zackb/go-appstoreconnect/appstoreconnect"
 	"github.com/zackb/go-appstoreconnect/encoding"
@@ -13,16 +19,52 @@ import (
 const (
 	CmdSalesReport   string = "SalesReport"
 	CmdFinanceReport string = "FinanceReport"
+	CmdParse         string = "parse"
+	CmdToken         string = "token"
+	CmdGenerate      string = "generate"
+	CmdExport        string = "export"
+	CmdDaemon        string = "daemon"
+	CmdServe         string = "serve"
 )
 
+// commands parsing their own flags, given the arguments after the command name
+var commands = map[string]func(args []string) error{
+	CmdParse:    parseFiles,
+	CmdToken:    printToken,
+	CmdGenerate: generate,
+	CmdDaemon:   daemon,
+	CmdServe:    serve,
+}
+
 type cmd struct {
 	service         string
 	credentialsFile string
 	outputFormat    encoding.Encoding
 	timeRange       *appstoreconnect.TimeRange
+	syncFile        string
+	cacheDir        string
+	groupBy         string
+	currency        string
+	ratesFile       string
+	plan            bool
+	vendors         []string
+	profile         string
+	flagCreds       appstoreconnect.Credentials
+	verbose         bool
+	saveRawDir      string
+	exportTo        string
+	finance         bool
+	overwrite       bool
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			checkError(run(os.Args[2:]))
+			return
+		}
+	}
+
 	c, err := parseCmd()
 	if err != nil {
 		flag.Usage()
@@ -30,27 +72,133 @@ func main() {
 		return
 	}
 
-	client, err := appstoreconnect.NewClientFromCredentialsFile(c.credentialsFile)
-	if checkError(err) {
-		return
+	opts := []appstoreconnect.ClientOption{}
+	if c.verbose {
+		opts = append(opts, appstoreconnect.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	}
+	if c.cacheDir != "" {
+		cache, err := appstoreconnect.NewDiskCache(c.cacheDir)
+		if checkError(err) {
+			return
+		}
+		opts = append(opts, appstoreconnect.WithCache(cache))
 	}
 
-	e, err := c.execute(client)
+	if c.saveRawDir != "" {
+		opts = append(opts, appstoreconnect.WithRawHandler(saveRaw(c.saveRawDir)))
+	}
+
+	client, err := c.newClient(opts)
 	if checkError(err) {
 		return
 	}
 
-	b, err := e.ToEncoding(c.outputFormat)
-	if checkError(err) {
+	if c.service == CmdExport {
+		checkError(c.export(client))
 		return
 	}
 
-	fmt.Println(string(b))
+	checkError(c.report(client, os.Stdout))
+}
+
+// report fetches the reports of the command and writes them to w. A sync which fails part way
+// has checkpointed the periods before the failure, so those are written before the error is
+// returned or the next run would skip them.
+func (c *cmd) report(client *appstoreconnect.Client, w io.Writer) error {
+	e, err := c.execute(client)
+	if errors.Is(err, appstoreconnect.ErrNotYetPublished) {
+		// output what is available, the rest will be there later
+		fmt.Fprintln(os.Stderr, err)
+		err = nil
+	}
+	if err != nil && (c.syncFile == "" || e == nil) {
+		return err
+	}
+
+	var convErr error
+	if c.currency != "" {
+		e, convErr = normalize(e, c.currency, c.ratesFile)
+		if convErr != nil {
+			return errors.Join(err, convErr)
+		}
+	}
+
+	if c.groupBy != "" {
+		e, convErr = groupBy(e, c.groupBy)
+		if convErr != nil {
+			return errors.Join(err, convErr)
+		}
+	}
+
+	return errors.Join(writeOutput(w, e, c.outputFormat), err)
+}
+
+func output(e encoding.Encodable, format encoding.Encoding) error {
+	return writeOutput(os.Stdout, e, format)
+}
+
+func writeOutput(w io.Writer, e encoding.Encodable, format encoding.Encoding) error {
+	b, err := e.ToEncoding(format)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// newClient creates a client from flags, ASC_* environment variables and the credentials file,
+// in that order, or from one profile of an accounts file with -profile
+func (c *cmd) newClient(opts []appstoreconnect.ClientOption) (*appstoreconnect.Client, error) {
+	if c.profile == "" {
+		if len(c.vendors) > 0 {
+			c.flagCreds.VendorNumber = c.vendors[0]
+		}
+		creds, err := appstoreconnect.ResolveCredentials(&c.flagCreds, c.credentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		return appstoreconnect.NewClient(creds, opts...)
+	}
+
+	accounts, err := appstoreconnect.LoadAccounts(c.credentialsFile, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return accounts.Client(c.profile)
 }
 
 func (c *cmd) execute(client *appstoreconnect.Client) (encoding.Encodable, error) {
+	if len(c.vendors) == 1 {
+		client = client.WithVendor(c.vendors[0])
+	}
+
+	if c.syncFile != "" {
+		return c.sync(client)
+	}
+
+	if len(c.vendors) > 1 {
+		switch c.service {
+		case CmdSalesReport:
+			return client.SalesReport.GetVendors(
+				c.vendors,
+				c.timeRange,
+				appstoreconnect.ReportSales,
+				appstoreconnect.SubReportSummary)
+		case CmdFinanceReport:
+			return client.FinanceReport.GetVendors(c.vendors, c.timeRange, "US")
+		}
+	}
+
 	switch c.service {
 	case CmdSalesReport:
+		if c.plan {
+			return client.SalesReport.GetPlanned(
+				c.timeRange,
+				appstoreconnect.ReportSales,
+				appstoreconnect.SubReportSummary,
+				appstoreconnect.PlanOptions{})
+		}
 		return client.SalesReport.GetRange(
 			c.timeRange,
 			appstoreconnect.ReportSales,
@@ -63,6 +211,116 @@ func (c *cmd) execute(client *appstoreconnect.Client) (encoding.Encodable, error
 	return nil, errors.New("No such command")
 }
 
+// sync fetches only the periods not already recorded in the checkpoint file, starting at the
+// beginning of the -d range the first time
+func (c *cmd) sync(client *appstoreconnect.Client) (encoding.Encodable, error) {
+	syncer := appstoreconnect.NewSyncer(client, appstoreconnect.NewFileCheckpointStore(c.syncFile))
+	syncer.Start = c.timeRange.Start
+
+	key := appstoreconnect.SyncKey{Frequency: c.timeRange.Frequency}
+	sales := &appstoreconnect.SalesReportResponse{}
+	finance := &appstoreconnect.FinanceReportResponse{}
+
+	switch c.service {
+	case CmdSalesReport:
+		key.ReportType = appstoreconnect.ReportSales
+		key.ReportSubType = appstoreconnect.SubReportSummary
+		err := syncer.Sync(key, func(_ appstoreconnect.SyncKey, _ time.Time, r encoding.Encodable) error {
+			sales.Reports = append(sales.Reports, r.(*appstoreconnect.SalesReportResponse).Reports...)
+			return nil
+		})
+		return sales, err
+	case CmdFinanceReport:
+		key.ReportType = appstoreconnect.ReportFinancial
+		key.RegionCode = "US"
+		err := syncer.Sync(key, func(_ appstoreconnect.SyncKey, _ time.Time, r encoding.Encodable) error {
+			finance.Reports = append(finance.Reports, r.(*appstoreconnect.FinanceReportResponse).Reports...)
+			return nil
+		})
+		return finance, err
+	default:
+		flag.Usage()
+	}
+	return nil, errors.New("No such command")
+}
+
+// export copies the reports of the -d range to the -to directory or s3://bucket/prefix, skipping
+// periods already there
+func (c *cmd) export(client *appstoreconnect.Client) error {
+	if c.exportTo == "" {
+		return errors.New("Must specify -to directory or s3://bucket/prefix")
+	}
+	sink, err := appstoreconnect.NewSink(c.exportTo)
+	if err != nil {
+		return err
+	}
+	exporter := appstoreconnect.NewExporter(client, sink)
+	exporter.Overwrite = c.overwrite
+
+	var stats appstoreconnect.ExportStats
+	if c.finance {
+		stats, err = exporter.ExportFinance(c.timeRange, "US")
+	} else {
+		stats, err = exporter.ExportSales(c.timeRange, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d, skipped %d already exported, %d without data\n", stats.Written, stats.Skipped, stats.Empty)
+	if errors.Is(err, appstoreconnect.ErrNotYetPublished) {
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	return err
+}
+
+// saveRaw writes each report file as apple served it next to a json file describing it
+func saveRaw(dir string) func(*appstoreconnect.RawReport) error {
+	return func(raw *appstoreconnect.RawReport) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, raw.FileName())
+		if err := os.WriteFile(path, raw.Body, 0644); err != nil {
+			return err
+		}
+		meta, err := json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path+".json", meta, 0644)
+	}
+}
+
+// groupBy pivots a sales report by a comma separated list of columns
+func groupBy(e encoding.Encodable, columns string) (encoding.Encodable, error) {
+	sales, ok := e.(*appstoreconnect.SalesReportResponse)
+	if !ok {
+		return nil, errors.New("Can only group sales reports")
+	}
+	return sales.Aggregate(strings.Split(columns, ",")...)
+}
+
+// normalize converts sales report proceeds to currency using the rates in ratesFile
+func normalize(e encoding.Encodable, currency string, ratesFile string) (encoding.Encodable, error) {
+	sales, ok := e.(*appstoreconnect.SalesReportResponse)
+	if !ok {
+		return nil, errors.New("Can only convert sales reports")
+	}
+	if ratesFile == "" {
+		return nil, errors.New("Must specify -rates to convert currency")
+	}
+
+	f, err := os.Open(ratesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rates, err := appstoreconnect.LoadExchangeRates(currency, f)
+	if err != nil {
+		return nil, err
+	}
+	return sales.Normalize(rates)
+}
+
 func parseCmd() (*cmd, error) {
 	c := cmd{}
 	var d string
@@ -75,10 +333,29 @@ func parseCmd() (*cmd, error) {
 
 	fs := flag.NewFlagSet(c.service, flag.ExitOnError)
 	fs.StringVar(&c.credentialsFile, "c", "credentials.yml", "path to credentials yaml file")
-	fs.StringVar(&d, "d", "", "date string")
+	credentialFlags(fs, &c.flagCreds)
+	var vendors string
+	fs.StringVar(&vendors, "vendor", "", "comma separated vendor numbers, overriding the credentials file")
+	fs.StringVar(&c.profile, "profile", "", "profile to use when -c is a multi account profiles file")
+	fs.StringVar(&d, "d", "", "date, range or expression like last-7d, mtd or 2024-01:")
 	fs.Var(&c.outputFormat, "o", "output format")
+	fs.StringVar(&c.cacheDir, "cache", "", "directory to cache raw reports in")
+	fs.StringVar(&c.groupBy, "group-by", "", "comma separated columns to sum units and proceeds by, e.g. sku,country")
+	fs.StringVar(&c.currency, "currency", "", "convert proceeds to this currency, e.g. USD")
+	fs.StringVar(&c.ratesFile, "rates", "", "csv of month,currency,rate used by -currency")
+	fs.BoolVar(&c.plan, "plan", false, "fetch the range with the fewest yearly, monthly, weekly and daily reports")
+	fs.StringVar(&c.saveRawDir, "save-raw", "", "directory to keep apple's report files in, with a .json of the url, headers and sha256")
+	fs.StringVar(&c.exportTo, "to", "", "export: directory or s3://bucket/prefix to export to")
+	fs.BoolVar(&c.finance, "finance", false, "export: finance rather than sales reports")
+	fs.BoolVar(&c.overwrite, "overwrite", false, "export: write periods again even if already exported")
+	fs.BoolVar(&c.verbose, "v", false, "log each request to stderr")
+	fs.StringVar(&c.syncFile, "sync", "", "checkpoint file; only fetch periods not yet synced")
 	fs.Parse(os.Args[2:])
 
+	if vendors != "" {
+		c.vendors = strings.Split(vendors, ",")
+	}
+
 	// default to json
 	if c.outputFormat == encoding.None {
 		c.outputFormat = encoding.Json
@@ -88,12 +365,24 @@ func parseCmd() (*cmd, error) {
 	if d == "" {
 		c.timeRange = appstoreconnect.Yesterday()
 	} else {
-		c.timeRange, err = appstoreconnect.ParseTimeRange(d)
+		p := appstoreconnect.RangeParser{}
+		if c.service == CmdFinanceReport || c.finance {
+			p.ReportType = appstoreconnect.ReportFinancial
+		}
+		c.timeRange, err = p.Parse(d)
 
 	}
 	return &c, err
 }
 
+// credentialFlags adds the flags which take precedence over the environment and credentials file
+func credentialFlags(fs *flag.FlagSet, creds *appstoreconnect.Credentials) {
+	fs.StringVar(&creds.KeyID, "key-id", "", "api key id, overriding "+appstoreconnect.EnvKeyID+" and the credentials file")
+	fs.StringVar(&creds.IssuerID, "issuer-id", "", "issuer id, overriding "+appstoreconnect.EnvIssuerID+" and the credentials file")
+	fs.StringVar(&creds.PrivKeyPath, "key", "", "path to the .p8 private key, overriding "+appstoreconnect.EnvPrivateKeyPath+" and the credentials file")
+	fs.StringVar((*string)(&creds.KeyType), "key-type", "", "team or individual, overriding "+appstoreconnect.EnvKeyType+" and the credentials file")
+}
+
 func checkError(e error) bool {
 	if e != nil {
 		fmt.Fprintln(os.Stderr, e)
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+const salesTsv = "Provider\tProvider Country\tSKU\tDeveloper\tTitle\tVersion\tProduct Type Identifier\tUnits\tDeveloper Proceeds\tBegin Date\tEnd Date\tCustomer Currency\tCountry Code\tCurrency of Proceeds\tApple Identifier\tCustomer Price\n" +
+	"APPLE\tUS\tcom.example.app\tExample\tExample App\t1.0\t1F\t3\t0.70\t05/01/2024\t05/01/2024\tUSD\tUS\tUSD\t123456\t0.99\n"
+
+func TestSyncWritesPeriodsBeforeFailure(t *testing.T) {
+	server := appstoreconnecttest.NewServer()
+	defer server.Close()
+	server.AddSalesReport(appstoreconnect.Daily, "2024-05-01", appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, salesTsv)
+
+	// the second day fails after the first was checkpointed
+	failing := func(next http.RoundTripper) http.RoundTripper {
+		return appstoreconnect.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if op, ok := appstoreconnect.OperationFromContext(req.Context()); ok && op.Filters["filter[reportDate]"] == "2024-05-02" {
+				return nil, errors.New("connection reset")
+			}
+			return next.RoundTrip(req)
+		})
+	}
+	client, err := server.Client(
+		appstoreconnect.WithClock(func() time.Time { return time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC) }),
+		appstoreconnect.WithMiddleware(failing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncFile := filepath.Join(t.TempDir(), "sync.json")
+	c := &cmd{
+		service:      CmdSalesReport,
+		outputFormat: encoding.Csv,
+		timeRange:    appstoreconnect.NewTimeRange(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), appstoreconnect.Daily),
+		syncFile:     syncFile,
+	}
+	var out bytes.Buffer
+	if err := c.report(client, &out); err == nil {
+		t.Error("expected the failure to be reported")
+	}
+	if !strings.Contains(out.String(), "com.example.app") {
+		t.Error("expected the checkpointed period to be written", out.String())
+	}
+
+	cp, err := appstoreconnect.NewFileCheckpointStore(syncFile).Load(appstoreconnect.SyncKey{
+		VendorNumber: appstoreconnecttest.VendorNumber, ReportType: appstoreconnect.ReportSales,
+		ReportSubType: appstoreconnect.SubReportSummary, Frequency: appstoreconnect.Daily})
+	if err != nil || cp == nil || !cp.Last.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the first day to be checkpointed %+v %v", cp, err)
+	}
+}
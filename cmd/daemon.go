@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// daemon runs the jobs of a config file on their schedules, serving /healthz and /metrics,
+// until SIGTERM or SIGINT, letting a run in progress finish the period it is exporting
+func daemon(args []string) error {
+	var configFile string
+	var verbose bool
+
+	fs := flag.NewFlagSet(CmdDaemon, flag.ExitOnError)
+	fs.StringVar(&configFile, "config", "daemon.yml", "path to the daemon yaml file")
+	fs.BoolVar(&verbose, "v", false, "log each request to stderr")
+	fs.Parse(args)
+
+	config, err := appstoreconnect.LoadDaemonConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	opts := []appstoreconnect.ClientOption{}
+	if verbose {
+		opts = append(opts, appstoreconnect.WithLogger(logger))
+	}
+	if config.Cache != "" {
+		cache, err := appstoreconnect.NewDiskCache(config.Cache)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, appstoreconnect.WithCache(cache))
+	}
+
+	c := &cmd{credentialsFile: config.Credentials, profile: config.Profile}
+	client, err := c.newClient(opts)
+	if err != nil {
+		return err
+	}
+
+	d, err := appstoreconnect.NewDaemon(client, appstoreconnect.NewFileCheckpointStore(config.Checkpoints), config)
+	if err != nil {
+		return err
+	}
+	d.Logger = logger
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	server := &http.Server{Addr: config.Listen, Handler: d.Handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+			stop()
+		}
+	}()
+	logger.Info("daemon started", "listen", config.Listen, "jobs", len(config.Jobs))
+
+	d.Run(ctx)
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		return err
+	}
+	select {
+	case err := <-serveErr:
+		return err
+	default:
+		logger.Info("daemon stopped")
+		return nil
+	}
+}
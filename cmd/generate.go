@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/appstoreconnecttest"
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// generate writes synthetic reports, either as one report on stdout or with -dir as gzipped
+// files per period named like the downloads from the App Store Connect website
+func generate(args []string) error {
+	var format encoding.Encoding
+	var d, dir, region string
+	var finance bool
+	var seed uint64
+
+	fs := flag.NewFlagSet(CmdGenerate, flag.ExitOnError)
+	fs.StringVar(&d, "d", "yesterday", "date, range or expression like last-7d, mtd or 2024-01:2024-06")
+	fs.Uint64Var(&seed, "seed", 1, "seed, the same seed always generates the same reports")
+	fs.BoolVar(&finance, "finance", false, "generate finance rather than sales reports")
+	fs.StringVar(&region, "region", "US", "finance report region code, ZZ for every country")
+	fs.StringVar(&dir, "dir", "", "directory to write a gzipped report per period to")
+	fs.Var(&format, "o", "output format")
+	fs.Parse(args)
+
+	p := appstoreconnect.RangeParser{}
+	if finance {
+		p.ReportType = appstoreconnect.ReportFinancial
+	}
+	tr, err := p.Parse(d)
+	if err != nil {
+		return err
+	}
+	g := appstoreconnecttest.NewGenerator(seed)
+
+	if dir != "" {
+		return generateFiles(g, tr, finance, region, dir)
+	}
+	if format == encoding.None {
+		format = encoding.Json
+	}
+	if finance {
+		return output(g.Finance(tr, region), format)
+	}
+	return output(g.Sales(tr), format)
+}
+
+func generateFiles(g *appstoreconnecttest.Generator, tr *appstoreconnect.TimeRange, finance bool, region string, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	vendor := appstoreconnecttest.VendorNumber
+
+	if finance {
+		for p := range tr.Fiscal(appstoreconnect.AppleFiscalCalendar).Periods() {
+			name := appstoreconnect.FinanceReportFileName(vendor, p, region)
+			if err := writeGzip(filepath.Join(dir, name), g.FinanceTSV(p, region)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for p := range tr.Periods() {
+		name := appstoreconnect.SalesReportFileName(vendor, appstoreconnect.ReportSales, appstoreconnect.SubReportSummary, p)
+		if err := writeGzip(filepath.Join(dir, name), g.SalesTSV(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeGzip(path string, s string) error {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+	"github.com/zackb/go-appstoreconnect/encoding"
+)
+
+// parseFiles converts report files downloaded from the App Store Connect website
+func parseFiles(args []string) error {
+	var format encoding.Encoding
+	fs := flag.NewFlagSet(CmdParse, flag.ExitOnError)
+	fs.Var(&format, "o", "output format")
+
+	// allow flags before or after the file names
+	var files []string
+	for len(args) > 0 {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) > 0 {
+			files = append(files, args[0])
+			args = args[1:]
+		}
+	}
+
+	if len(files) == 0 {
+		return errors.New("Must specify a report file to parse")
+	}
+	if format == encoding.None {
+		format = encoding.Json
+	}
+
+	sales := &appstoreconnect.SalesReportResponse{}
+	finance := &appstoreconnect.FinanceReportResponse{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		r, err := appstoreconnect.ParseReport(f)
+		f.Close()
+		if err != nil {
+			return errors.New(file + ": " + err.Error())
+		}
+
+		switch r := r.(type) {
+		case *appstoreconnect.SalesReportResponse:
+			sales.Reports = append(sales.Reports, r.Reports...)
+		case *appstoreconnect.FinanceReportResponse:
+			finance.Reports = append(finance.Reports, r.Reports...)
+		}
+	}
+
+	if len(finance.Reports) > 0 && len(sales.Reports) > 0 {
+		return errors.New("Cannot mix sales and finance reports in one output")
+	}
+	if len(finance.Reports) > 0 {
+		return output(finance, format)
+	}
+	return output(sales, format)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// EnvServeTokens is a comma separated list of the api tokens serve accepts
+const EnvServeTokens = "ASC_SERVE_TOKENS"
+
+// serve runs an http server offering reports to holders of an api token rather than the key
+func serve(args []string) error {
+	c := &cmd{}
+	var listen, tokensFile string
+	var maxPeriods int
+
+	fs := flag.NewFlagSet(CmdServe, flag.ExitOnError)
+	fs.StringVar(&c.credentialsFile, "c", "credentials.yml", "path to credentials yaml file")
+	credentialFlags(fs, &c.flagCreds)
+	fs.StringVar(&c.profile, "profile", "", "profile to use when -c is a multi account profiles file")
+	fs.StringVar(&listen, "listen", ":8080", "address to listen on")
+	fs.StringVar(&c.cacheDir, "cache", "cache", "directory to cache raw reports in")
+	fs.StringVar(&tokensFile, "tokens", "", "file of api tokens, one per line, in addition to "+EnvServeTokens)
+	fs.IntVar(&maxPeriods, "max-periods", appstoreconnect.DefaultMaxPeriods, "most reports one request may fetch")
+	fs.BoolVar(&c.verbose, "v", false, "log each request to stderr")
+	fs.Parse(args)
+
+	tokens, err := serveTokens(tokensFile)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	opts := []appstoreconnect.ClientOption{}
+	if c.verbose {
+		opts = append(opts, appstoreconnect.WithLogger(logger))
+	}
+	cache, err := appstoreconnect.NewDiskCache(c.cacheDir)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, appstoreconnect.WithCache(cache))
+
+	client, err := c.newClient(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	reports := appstoreconnect.NewReportServer(client, tokens...)
+	reports.MaxPeriods = maxPeriods
+	server := &http.Server{Addr: listen, Handler: reports}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	logger.Info("serving reports", "listen", listen, "tokens", len(tokens))
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		return err
+	}
+	logger.Info("stopped serving")
+	return nil
+}
+
+// serveTokens reads the api tokens from the environment and file, refusing to serve without any
+func serveTokens(file string) ([]string, error) {
+	tokens := []string{}
+	for token := range strings.SplitSeq(os.Getenv(EnvServeTokens), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for line := range strings.Lines(string(b)) {
+			if token := strings.TrimSpace(line); token != "" && !strings.HasPrefix(token, "#") {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("Must specify api tokens with -tokens or " + EnvServeTokens)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/zackb/go-appstoreconnect/appstoreconnect"
+)
+
+// printToken prints a bearer token for trying requests by hand, e.g.
+//
+//	curl -H "Authorization: Bearer $(connect token)" https://api.appstoreconnect.apple.com/v1/apps
+func printToken(args []string) error {
+	var credentialsFile, scope string
+	creds := appstoreconnect.Credentials{}
+
+	fs := flag.NewFlagSet(CmdToken, flag.ExitOnError)
+	fs.StringVar(&credentialsFile, "c", "credentials.yml", "path to credentials yaml file")
+	credentialFlags(fs, &creds)
+	fs.StringVar(&scope, "scope", "", "comma separated requests to restrict the token to, e.g. 'GET /v1/salesReports'")
+	fs.Parse(args)
+
+	if scope != "" {
+		creds.Scope = strings.Split(scope, ",")
+	}
+
+	resolved, err := appstoreconnect.ResolveCredentials(&creds, credentialsFile)
+	if err != nil {
+		return err
+	}
+	client, err := appstoreconnect.NewClient(resolved)
+	if err != nil {
+		return err
+	}
+	token, err := client.Token()
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
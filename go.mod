@@ -4,6 +4,7 @@ go 1.26.1
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/lib/pq v1.12.3
 	golang.org/x/text v0.3.8
 	gopkg.in/yaml.v2 v2.2.8
 )